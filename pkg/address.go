@@ -0,0 +1,355 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type Network int
+
+const (
+	Mainnet Network = iota
+	Testnet
+	Signet
+	Regtest
+)
+
+func (n Network) String() string {
+	switch n {
+	case Mainnet:
+		return "mainnet"
+	case Testnet:
+		return "testnet"
+	case Signet:
+		return "signet"
+	case Regtest:
+		return "regtest"
+	}
+	return fmt.Sprintf("Network(%d)", int(n))
+}
+
+type networkParams struct {
+	pubKeyHashPrefix byte
+	scriptHashPrefix byte
+	bech32HRP        string
+}
+
+var networks = map[Network]networkParams{
+	Mainnet: {pubKeyHashPrefix: 0x00, scriptHashPrefix: 0x05, bech32HRP: "bc"},
+	Testnet: {pubKeyHashPrefix: 0x6f, scriptHashPrefix: 0xc4, bech32HRP: "tb"},
+	Signet:  {pubKeyHashPrefix: 0x6f, scriptHashPrefix: 0xc4, bech32HRP: "tb"},
+	Regtest: {pubKeyHashPrefix: 0x6f, scriptHashPrefix: 0xc4, bech32HRP: "bcrt"},
+}
+
+// AddressType uses the names electrs reports as scriptpubkey_type.
+type AddressType string
+
+const (
+	AddressP2PKH  AddressType = "p2pkh"
+	AddressP2SH   AddressType = "p2sh"
+	AddressP2WPKH AddressType = "v0_p2wpkh"
+	AddressP2WSH  AddressType = "v0_p2wsh"
+	AddressP2TR   AddressType = "v1_p2tr"
+)
+
+var (
+	// ErrInvalidAddress is matched by every AddressError.
+	ErrInvalidAddress        = errors.New("invalid address")
+	ErrBadChecksum           = errors.New("bad checksum")
+	ErrWrongNetwork          = errors.New("wrong network")
+	ErrUnknownWitnessVersion = errors.New("unknown witness version")
+	ErrInvalidWitnessProgram = errors.New("invalid witness program")
+)
+
+// AddressError is returned when decoding an address fails. Err is one of
+// ErrBadChecksum, ErrWrongNetwork, ErrUnknownWitnessVersion,
+// ErrInvalidWitnessProgram or a description of a malformed encoding.
+type AddressError struct {
+	Address Address
+	Network Network
+	Err     error
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("invalid %s address %q: %s", e.Network, e.Address, e.Err.Error())
+}
+
+func (e *AddressError) Is(target error) bool {
+	return target == ErrInvalidAddress
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// DecodedAddress is an address split into its type and the hash or witness
+// program it commits to.
+type DecodedAddress struct {
+	Address        Address
+	Network        Network
+	Type           AddressType
+	WitnessVersion int
+	// Program is the hash160 for P2PKH and P2SH, the witness program
+	// otherwise.
+	Program []byte
+}
+
+// Decode parses a Base58Check (P2PKH, P2SH), bech32 (P2WPKH, P2WSH) or
+// bech32m (P2TR) address for network.
+func (a Address) Decode(network Network) (*DecodedAddress, error) {
+	params, ok := networks[network]
+	if !ok {
+		return nil, &AddressError{Address: a, Network: network, Err: errors.New("unknown network")}
+	}
+
+	var decoded *DecodedAddress
+	var err error
+	if i := strings.LastIndexByte(string(a), '1'); i > 0 && isBech32HRP(strings.ToLower(string(a[:i]))) {
+		decoded, err = decodeSegwitAddress(string(a), params)
+	} else {
+		decoded, err = decodeBase58Address(string(a), params)
+	}
+	if err != nil {
+		return nil, &AddressError{Address: a, Network: network, Err: err}
+	}
+	decoded.Address, decoded.Network = a, network
+	return decoded, nil
+}
+
+func (a Address) Validate(network Network) error {
+	_, err := a.Decode(network)
+	return err
+}
+
+func (a Address) ScriptPubKey(network Network) ([]byte, error) {
+	decoded, err := a.Decode(network)
+	if err != nil {
+		return nil, err
+	}
+	return decoded.ScriptPubKey(), nil
+}
+
+// ScriptHash returns the Electrum script hash used by the /scripthash
+// endpoints and the Electrum protocol.
+func (a Address) ScriptHash(network Network) (ScriptHash, error) {
+	script, err := a.ScriptPubKey(network)
+	if err != nil {
+		return "", err
+	}
+	return ScriptHashFromScript(script), nil
+}
+
+func (d *DecodedAddress) ScriptPubKey() []byte {
+	switch d.Type {
+	case AddressP2PKH:
+		// OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG
+		script := append([]byte{0x76, 0xa9, byte(len(d.Program))}, d.Program...)
+		return append(script, 0x88, 0xac)
+	case AddressP2SH:
+		// OP_HASH160 <hash> OP_EQUAL
+		script := append([]byte{0xa9, byte(len(d.Program))}, d.Program...)
+		return append(script, 0x87)
+	}
+	version := byte(0x00)
+	if d.WitnessVersion > 0 {
+		version = 0x50 + byte(d.WitnessVersion)
+	}
+	return append([]byte{version, byte(len(d.Program))}, d.Program...)
+}
+
+// ScriptHashFromScript returns the Electrum script hash of a scriptPubKey:
+// its SHA256 in reversed byte order.
+func ScriptHashFromScript(script []byte) ScriptHash {
+	sum := sha256.Sum256(script)
+	return ScriptHash(hex.EncodeToString(reverseBytes(sum[:])))
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func decodeBase58Address(address string, params networkParams) (*DecodedAddress, error) {
+	payload, err := base58CheckDecode(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) != 21 {
+		return nil, fmt.Errorf("invalid payload length %d", len(payload))
+	}
+
+	decoded := &DecodedAddress{Program: payload[1:]}
+	switch payload[0] {
+	case params.pubKeyHashPrefix:
+		decoded.Type = AddressP2PKH
+	case params.scriptHashPrefix:
+		decoded.Type = AddressP2SH
+	default:
+		for _, other := range networks {
+			if payload[0] == other.pubKeyHashPrefix || payload[0] == other.scriptHashPrefix {
+				return nil, ErrWrongNetwork
+			}
+		}
+		return nil, fmt.Errorf("unknown version byte 0x%02x", payload[0])
+	}
+	return decoded, nil
+}
+
+func base58CheckDecode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty address")
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base58Alphabet, s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", s[i])
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	var zeros int
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 5 {
+		return nil, errors.New("address too short")
+	}
+
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, ErrBadChecksum
+	}
+	return payload, nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+func isBech32HRP(hrp string) bool {
+	for _, params := range networks {
+		if hrp == params.bech32HRP {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegwitAddress(address string, params networkParams) (*DecodedAddress, error) {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return nil, errors.New("mixed case")
+	}
+	if len(address) > 90 {
+		return nil, errors.New("address too long")
+	}
+	address = strings.ToLower(address)
+
+	sep := strings.LastIndexByte(address, '1')
+	hrp, data := address[:sep], address[sep+1:]
+	if hrp != params.bech32HRP {
+		return nil, ErrWrongNetwork
+	}
+	if len(data) < 7 {
+		return nil, errors.New("address too short")
+	}
+
+	values := make([]byte, len(data))
+	for i := 0; i < len(data); i++ {
+		v := strings.IndexByte(bech32Charset, data[i])
+		if v < 0 {
+			return nil, fmt.Errorf("invalid bech32 character %q", data[i])
+		}
+		values[i] = byte(v)
+	}
+
+	constant := bech32Polymod(append(bech32ExpandHRP(hrp), values...))
+	if constant != bech32Constant && constant != bech32mConstant {
+		return nil, ErrBadChecksum
+	}
+
+	version := int(values[0])
+	if version > 16 {
+		return nil, ErrUnknownWitnessVersion
+	}
+	// BIP350: version 0 uses bech32, later versions bech32m.
+	if (version == 0) != (constant == bech32Constant) {
+		return nil, ErrBadChecksum
+	}
+	program, err := convertBits(values[1:len(values)-6], 5, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := &DecodedAddress{WitnessVersion: version, Program: program}
+	switch {
+	case version == 0 && len(program) == 20:
+		decoded.Type = AddressP2WPKH
+	case version == 0 && len(program) == 32:
+		decoded.Type = AddressP2WSH
+	case version == 1 && len(program) == 32:
+		decoded.Type = AddressP2TR
+	case version <= 1:
+		return nil, fmt.Errorf("%w: %d bytes for version %d", ErrInvalidWitnessProgram, len(program), version)
+	default:
+		return nil, fmt.Errorf("%w %d", ErrUnknownWitnessVersion, version)
+	}
+	return decoded, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// convertBits regroups data from from-bit to to-bit values, rejecting
+// non-zero or excess padding.
+func convertBits(data []byte, from, to uint) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+	converted := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte(acc>>bits&maxv))
+		}
+	}
+	if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidWitnessProgram)
+	}
+	return converted, nil
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestAddress_ScriptHash(t *testing.T) {
+	cases := []struct {
+		address      Address
+		network      Network
+		addressType  AddressType
+		scriptPubKey string
+		scriptHash   ScriptHash
+	}{
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Mainnet, AddressP2PKH,
+			"76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac",
+			"8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"},
+		{"3CNHUhP3uyB9EUtRLsmvFUmvGdjGdkTxJw", Mainnet, AddressP2SH,
+			"a914751e76e8199196d454941c45d1b3a323f1433bd687",
+			"5a29e8e4026531293483370fe8133bda5c2e28c882b597902515f9b9fcfa7e95"},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", Mainnet, AddressP2WPKH,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			"9623df75239b5daa7f5f03042d325b51498c4bb7059c7748b17049bf96f73888"},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", Mainnet, AddressP2WPKH,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			"9623df75239b5daa7f5f03042d325b51498c4bb7059c7748b17049bf96f73888"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", Testnet, AddressP2WSH,
+			"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+			"94ef09765c3092cd7a1d9f7a6e1ff861e446fd795d1e8a93f427c42df7ffe123"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", Mainnet, AddressP2TR,
+			"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"a12cf1aa7c74a6e9f54984646526173abed2a9f4a4862dc83eb94e8e8ef5220a"},
+		{"mpXwg4jMtRhuSpVq4xS3HFHmCmWp9NyGKt", Signet, AddressP2PKH,
+			"76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac",
+			"8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"},
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", Regtest, AddressP2WPKH,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			"9623df75239b5daa7f5f03042d325b51498c4bb7059c7748b17049bf96f73888"},
+	}
+
+	for _, tc := range cases {
+		decoded, err := tc.address.Decode(tc.network)
+		if err != nil {
+			t.Errorf("%s: %s", tc.address, err.Error())
+			continue
+		}
+		if decoded.Type != tc.addressType {
+			t.Errorf("%s: expected type %s, got %s", tc.address, tc.addressType, decoded.Type)
+		}
+		if script := hex.EncodeToString(decoded.ScriptPubKey()); script != tc.scriptPubKey {
+			t.Errorf("%s: expected script %s, got %s", tc.address, tc.scriptPubKey, script)
+		}
+
+		hash, err := tc.address.ScriptHash(tc.network)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if hash != tc.scriptHash {
+			t.Errorf("%s: expected script hash %s, got %s", tc.address, tc.scriptHash, hash)
+		}
+	}
+}
+
+func TestAddress_Validate(t *testing.T) {
+	cases := []struct {
+		address  Address
+		network  Network
+		sentinel error
+	}{
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", Mainnet, ErrBadChecksum},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", Mainnet, ErrBadChecksum},
+		// A taproot program encoded with bech32 instead of bech32m.
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", Mainnet, ErrBadChecksum},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Testnet, ErrWrongNetwork},
+		{"mpXwg4jMtRhuSpVq4xS3HFHmCmWp9NyGKt", Mainnet, ErrWrongNetwork},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", Testnet, ErrWrongNetwork},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", Regtest, ErrWrongNetwork},
+		{"bc1zw508d6qejxtdg4y5r3zarvary0c5xw7k0w7t5p", Mainnet, ErrUnknownWitnessVersion},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kqypqxpq9d6lzpa", Mainnet, ErrInvalidWitnessProgram},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7KV8F3T4", Mainnet, ErrInvalidAddress},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7Divf0a", Mainnet, ErrInvalidAddress},
+		{"", Mainnet, ErrInvalidAddress},
+	}
+
+	for _, tc := range cases {
+		err := tc.address.Validate(tc.network)
+		if !errors.Is(err, tc.sentinel) {
+			t.Errorf("%q on %s: expected %v, got %v", tc.address, tc.network, tc.sentinel, err)
+		}
+		if !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%q on %s: expected ErrInvalidAddress, got %v", tc.address, tc.network, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAmountOverflow = errors.New("amount overflows int64")
+	ErrInvalidAmount  = errors.New("invalid amount")
+)
+
+const (
+	SatoshiPerBTC Satoshi = 100000000
+	// MaxMoney is the 21 million BTC cap; any valid amount lies in
+	// [0, MaxMoney].
+	MaxMoney = 21000000 * SatoshiPerBTC
+)
+
+// Satoshi is an amount in satoshis, the unit electrs reports values, fees
+// and balances in. It is used for every amount in this package.
+//
+// Migrating from the previous field types: amounts that were int32 or
+// int64 (Transaction.Fee, TransactionOut.Value, MemPoolOverviewData.Fee,
+// UnspentTransactionOutput.Value, ElectrumBalance, ...) convert with
+// int64(v) and Satoshi(n); the JSON encoding is unchanged. ChainStats sums
+// were float64 and are now exact, so float64(v) keeps old arithmetic
+// compiling. TransactionStatus.BlockTime and Block.Timestamp are now int64
+// Unix times; use their Time methods for a time.Time.
+type Satoshi int64
+
+// ParseBTC parses a decimal BTC amount such as "0.0015" or "-21000000"
+// exactly, without going through float64.
+func ParseBTC(s string) (Satoshi, error) {
+	digits := strings.TrimPrefix(s, "-")
+	negative := len(digits) != len(s)
+	whole, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, fraction = digits[:i], digits[i+1:]
+	}
+	if whole == "" && fraction == "" || len(fraction) > 8 || strings.ContainsAny(whole+fraction, "+-") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	var sats uint64
+	if whole != "" {
+		n, err := strconv.ParseUint(whole, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		if n > uint64(math.MaxInt64/SatoshiPerBTC) {
+			return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+		}
+		sats = n * uint64(SatoshiPerBTC)
+	}
+	if fraction != "" {
+		n, err := strconv.ParseUint(fraction+strings.Repeat("0", 8-len(fraction)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		sats += n
+	}
+	if sats > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	if negative {
+		return -Satoshi(sats), nil
+	}
+	return Satoshi(sats), nil
+}
+
+// FromBTC converts a float64 BTC amount, as the Electrum protocol reports
+// fee rates, rounding to the nearest satoshi.
+func FromBTC(btc float64) (Satoshi, error) {
+	sats := math.Round(btc * float64(SatoshiPerBTC))
+	if math.IsNaN(sats) || sats >= math.MaxInt64 || sats < math.MinInt64 {
+		return 0, fmt.Errorf("%w: %v BTC", ErrAmountOverflow, btc)
+	}
+	return Satoshi(sats), nil
+}
+
+// BTC returns the amount in BTC. The float64 is exact for every amount up
+// to MaxMoney but meant for display and fee-rate math only.
+func (s Satoshi) BTC() float64 {
+	return float64(s) / float64(SatoshiPerBTC)
+}
+
+// FormatBTC renders the amount with all 8 decimals, e.g. "0.00150000".
+func (s Satoshi) FormatBTC() string {
+	sign := ""
+	abs := uint64(s)
+	if s < 0 {
+		sign = "-"
+		abs = uint64(-s)
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, abs/uint64(SatoshiPerBTC), abs%uint64(SatoshiPerBTC))
+}
+
+func (s Satoshi) String() string {
+	return s.FormatBTC() + " BTC"
+}
+
+// Valid reports whether s lies in [0, MaxMoney].
+func (s Satoshi) Valid() bool {
+	return s >= 0 && s <= MaxMoney
+}
+
+// Add returns s+o, or ErrAmountOverflow instead of wrapping around.
+func (s Satoshi) Add(o Satoshi) (Satoshi, error) {
+	sum := s + o
+	if (o > 0 && sum < s) || (o < 0 && sum > s) {
+		return 0, fmt.Errorf("%w: %d + %d", ErrAmountOverflow, s, o)
+	}
+	return sum, nil
+}
+
+func (s Satoshi) Sub(o Satoshi) (Satoshi, error) {
+	diff := s - o
+	if (o > 0 && diff > s) || (o < 0 && diff < s) {
+		return 0, fmt.Errorf("%w: %d - %d", ErrAmountOverflow, s, o)
+	}
+	return diff, nil
+}
+
+func (s Satoshi) Mul(n int64) (Satoshi, error) {
+	if s == 0 || n == 0 {
+		return 0, nil
+	}
+	product := s * Satoshi(n)
+	if product/Satoshi(n) != s || (n == -1 && s == math.MinInt64) {
+		return 0, fmt.Errorf("%w: %d * %d", ErrAmountOverflow, s, n)
+	}
+	return product, nil
+}
+
+// Sum adds amounts, failing on overflow.
+func Sum(amounts ...Satoshi) (Satoshi, error) {
+	var total Satoshi
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// Time returns BlockTime as a UTC time, or the zero time for an unconfirmed
+// transaction.
+func (s TransactionStatus) Time() time.Time {
+	if !s.Confirmed {
+		return time.Time{}
+	}
+	return time.Unix(s.BlockTime, 0).UTC()
+}
+
+func (b *Block) Time() time.Time {
+	return time.Unix(b.Timestamp, 0).UTC()
+}
@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseBTC(t *testing.T) {
+	cases := map[string]Satoshi{
+		"0.0015":      150000,
+		"1":           SatoshiPerBTC,
+		".5":          50000000,
+		"21000000":    MaxMoney,
+		"-0.00000001": -1,
+		"10000000.1":  1000000010000000,
+		"0.12345678":  12345678,
+	}
+	for s, expected := range cases {
+		if amount, err := ParseBTC(s); err != nil || amount != expected {
+			t.Errorf("%s: expected %d, got %d %v", s, expected, amount, err)
+		}
+	}
+
+	for _, s := range []string{"", ".", "1.123456789", "1e8", "--1", "+1", "1.-5", "abc"} {
+		if _, err := ParseBTC(s); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("%q: expected ErrInvalidAmount, got %v", s, err)
+		}
+	}
+	if _, err := ParseBTC("92233720368.54775808"); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+}
+
+func TestSatoshi_Format(t *testing.T) {
+	if s := Satoshi(150000).String(); s != "0.00150000 BTC" {
+		t.Errorf("unexpected %s", s)
+	}
+	if s := Satoshi(-2100000000000001).FormatBTC(); s != "-21000000.00000001" {
+		t.Errorf("unexpected %s", s)
+	}
+	if btc := Satoshi(12345678).BTC(); btc != 0.12345678 {
+		t.Errorf("unexpected %v", btc)
+	}
+	if amount, err := FromBTC(0.00001234); err != nil || amount != 1234 {
+		t.Errorf("unexpected %d %v", amount, err)
+	}
+	if _, err := FromBTC(math.Inf(1)); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+	if !MaxMoney.Valid() || (MaxMoney + 1).Valid() || Satoshi(-1).Valid() {
+		t.Errorf("unexpected Valid results")
+	}
+}
+
+func TestSatoshi_Arithmetic(t *testing.T) {
+	if sum, err := Sum(1, 2, 3); err != nil || sum != 6 {
+		t.Errorf("unexpected %d %v", sum, err)
+	}
+	if _, err := Satoshi(math.MaxInt64).Add(1); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+	if _, err := Satoshi(math.MinInt64).Sub(1); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+	if diff, err := Satoshi(5).Sub(7); err != nil || diff != -2 {
+		t.Errorf("unexpected %d %v", diff, err)
+	}
+	if product, err := Satoshi(3).Mul(-4); err != nil || product != -12 {
+		t.Errorf("unexpected %d %v", product, err)
+	}
+	if _, err := MaxMoney.Mul(math.MaxInt32); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+	if _, err := Satoshi(math.MinInt64).Mul(-1); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("expected ErrAmountOverflow, got %v", err)
+	}
+}
+
+func TestAmountFields_JSON(t *testing.T) {
+	var stats ChainStats
+	// Above 2^53, where the previous float64 sums lost precision.
+	if err := json.Unmarshal([]byte(`{"funded_txo_sum":9007199254740993,"spent_txo_sum":1}`), &stats); err != nil {
+		t.Fatal(err.Error())
+	}
+	if stats.FoundedTxoSum != 9007199254740993 {
+		t.Errorf("unexpected sum %d", stats.FoundedTxoSum)
+	}
+
+	var tx Transaction
+	if err := json.Unmarshal([]byte(`{"fee":3000000000,"status":{"confirmed":true,"block_time":4294967296}}`), &tx); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tx.Fee != 3000000000 {
+		t.Errorf("unexpected fee %d", tx.Fee)
+	}
+	if !tx.Status.Time().Equal(time.Unix(4294967296, 0)) {
+		t.Errorf("unexpected time %s", tx.Status.Time())
+	}
+	if !(TransactionStatus{BlockTime: 1}).Time().IsZero() {
+		t.Errorf("expected zero time for an unconfirmed status")
+	}
+	if block := (&Block{Timestamp: 1231006505}); block.Time() != time.Date(2009, 1, 3, 18, 15, 5, 0, time.UTC) {
+		t.Errorf("unexpected block time %s", block.Time())
+	}
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultBatchWorkers = 8
+
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	workers int
+}
+
+// BatchConcurrency bounds the number of requests a batch helper has in
+// flight at once; the default is 8.
+func BatchConcurrency(workers int) BatchOption {
+	return func(c *batchConfig) { c.workers = workers }
+}
+
+// AddressInfoResult is the outcome of one lookup of GetAddressInfos:
+// either Info or Err is set.
+type AddressInfoResult struct {
+	Info *AddressInfo
+	Err  error
+}
+
+func (c *HTTPClient) GetAddressInfos(addresses []Address, opts ...BatchOption) map[Address]AddressInfoResult {
+	return GetAddressInfos(context.Background(), c, addresses, opts...)
+}
+
+func (c *HTTPClient) GetAddressInfosCtx(ctx context.Context, addresses []Address, opts ...BatchOption) map[Address]AddressInfoResult {
+	return GetAddressInfos(ctx, c, addresses, opts...)
+}
+
+// GetAddressInfos looks up every distinct address concurrently and returns
+// one result per address, so that a failed lookup does not fail the rest of
+// the batch. Once ctx is done the remaining lookups fail with its error.
+func GetAddressInfos(ctx context.Context, api AddressAPI, addresses []Address, opts ...BatchOption) map[Address]AddressInfoResult {
+	config := batchConfig{workers: defaultBatchWorkers}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	unique := make([]Address, 0, len(addresses))
+	seen := make(map[Address]bool, len(addresses))
+	for _, address := range addresses {
+		if !seen[address] {
+			seen[address] = true
+			unique = append(unique, address)
+		}
+	}
+	workers := config.workers
+	if workers > len(unique) {
+		workers = len(unique)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make(map[Address]AddressInfoResult, len(unique))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan Address)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range jobs {
+				info, err := api.GetAddressInfoCtx(ctx, address)
+				mu.Lock()
+				results[address] = AddressInfoResult{Info: info, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, address := range unique {
+		jobs <- address
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
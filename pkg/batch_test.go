@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetAddressInfos(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address := strings.TrimPrefix(r.URL.Path, "/address/")
+		mu.Lock()
+		requests[address]++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+		if address == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Invalid Bitcoin address"))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"address":%q,"chain_stats":{"tx_count":1}}`, address)
+	}))
+	defer server.Close()
+
+	addresses := []Address{"bad"}
+	for i := 0; i < 20; i++ {
+		addresses = append(addresses, Address(fmt.Sprintf("a%d", i%10)))
+	}
+	results := NewHTTPClient(server.URL, false).GetAddressInfos(addresses, BatchConcurrency(3))
+
+	if len(results) != 11 {
+		t.Fatalf("expected 11 results, got %d", len(results))
+	}
+	if result := results["bad"]; result.Info != nil || !errors.Is(result.Err, ErrBadRequest) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	for i := 0; i < 10; i++ {
+		address := Address(fmt.Sprintf("a%d", i))
+		if result := results[address]; result.Err != nil || result.Info.Address != address || result.Info.ChainStats.TxCount != 1 {
+			t.Errorf("%s: unexpected result %+v", address, result)
+		}
+	}
+	for address, n := range requests {
+		if n != 1 {
+			t.Errorf("%s requested %d times", address, n)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("expected at most 3 requests in flight, got %d", maxInFlight)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results := GetAddressInfos(ctx, fixtureClient, []Address{"a0"}); !errors.Is(results["a0"].Err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", results["a0"].Err)
+	}
+	if results := GetAddressInfos(ctx, fixtureClient, nil); len(results) != 0 {
+		t.Errorf("unexpected results %v", results)
+	}
+}
@@ -1,30 +1,36 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 type HTTPClient struct {
 	Client *resty.Client
+	retry  *RetryPolicy
 }
 
 func NewHTTPClient(hostUrl string, debugMode bool) *HTTPClient {
-	restClient := resty.New()
-	restClient.SetDebug(debugMode)
-	restClient.SetHostURL(hostUrl)
-	restClient.SetHeader("Accept", "application/json")
-	restClient.SetContentLength(true)
-	return &HTTPClient{restClient}
+	return New(hostUrl, WithDebug(debugMode))
 }
 
 func (c *HTTPClient) GetTransaction(txID TxID) (*Transaction, error) {
+	return c.GetTransactionCtx(context.Background(), txID)
+}
+
+func (c *HTTPClient) GetTransactionCtx(ctx context.Context, txID TxID) (*Transaction, error) {
 	uri := fmt.Sprintf("/tx/%s", txID)
-	result, err := c.doGet(uri, &Transaction{})
+	result, err := c.doGet(ctx, uri, &Transaction{})
 	if err != nil {
 		return nil, err
 	}
@@ -32,8 +38,12 @@ func (c *HTTPClient) GetTransaction(txID TxID) (*Transaction, error) {
 }
 
 func (c *HTTPClient) GetTransactionStatus(txID TxID) (*TransactionStatus, error) {
+	return c.GetTransactionStatusCtx(context.Background(), txID)
+}
+
+func (c *HTTPClient) GetTransactionStatusCtx(ctx context.Context, txID TxID) (*TransactionStatus, error) {
 	uri := fmt.Sprintf("/tx/%s/status", txID)
-	result, err := c.doGet(uri, &TransactionStatus{})
+	result, err := c.doGet(ctx, uri, &TransactionStatus{})
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +51,12 @@ func (c *HTTPClient) GetTransactionStatus(txID TxID) (*TransactionStatus, error)
 }
 
 func (c *HTTPClient) GetTransactionHex(txID TxID) (TxHex, error) {
+	return c.GetTransactionHexCtx(context.Background(), txID)
+}
+
+func (c *HTTPClient) GetTransactionHexCtx(ctx context.Context, txID TxID) (TxHex, error) {
 	uri := fmt.Sprintf("/tx/%s/hex", txID)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return "", err
 	}
@@ -50,8 +64,12 @@ func (c *HTTPClient) GetTransactionHex(txID TxID) (TxHex, error) {
 }
 
 func (c *HTTPClient) GetTransactionMerkleProof(txID TxID) (*TransactionMerkleProof, error) {
+	return c.GetTransactionMerkleProofCtx(context.Background(), txID)
+}
+
+func (c *HTTPClient) GetTransactionMerkleProofCtx(ctx context.Context, txID TxID) (*TransactionMerkleProof, error) {
 	uri := fmt.Sprintf("/tx/%s/merkle-proof", txID)
-	result, err := c.doGet(uri, &TransactionMerkleProof{})
+	result, err := c.doGet(ctx, uri, &TransactionMerkleProof{})
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +77,12 @@ func (c *HTTPClient) GetTransactionMerkleProof(txID TxID) (*TransactionMerklePro
 }
 
 func (c *HTTPClient) GetTransactionOutSpend(txID TxID, vOut int32) (*TransactionOutSpend, error) {
+	return c.GetTransactionOutSpendCtx(context.Background(), txID, vOut)
+}
+
+func (c *HTTPClient) GetTransactionOutSpendCtx(ctx context.Context, txID TxID, vOut int32) (*TransactionOutSpend, error) {
 	uri := fmt.Sprintf("/tx/%s/outspend/%d", txID, vOut)
-	result, err := c.doGet(uri, &TransactionOutSpend{})
+	result, err := c.doGet(ctx, uri, &TransactionOutSpend{})
 	if err != nil {
 		return nil, err
 	}
@@ -68,14 +90,18 @@ func (c *HTTPClient) GetTransactionOutSpend(txID TxID, vOut int32) (*Transaction
 }
 
 func (c *HTTPClient) GetTransactionOutSpends(txID TxID) ([]*TransactionOutSpend, error) {
+	return c.GetTransactionOutSpendsCtx(context.Background(), txID)
+}
+
+func (c *HTTPClient) GetTransactionOutSpendsCtx(ctx context.Context, txID TxID) ([]*TransactionOutSpend, error) {
 	uri := fmt.Sprintf("/tx/%s/outspends", txID)
 	txOutSpends := make([]*TransactionOutSpend, 0)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(result, &txOutSpends)
+	err = decode(uri, result, &txOutSpends)
 	if err != nil {
 		return nil, err
 	}
@@ -84,8 +110,12 @@ func (c *HTTPClient) GetTransactionOutSpends(txID TxID) ([]*TransactionOutSpend,
 }
 
 func (c *HTTPClient) GetAddressInfo(address Address) (*AddressInfo, error) {
+	return c.GetAddressInfoCtx(context.Background(), address)
+}
+
+func (c *HTTPClient) GetAddressInfoCtx(ctx context.Context, address Address) (*AddressInfo, error) {
 	uri := fmt.Sprintf("/address/%s", address)
-	result, err := c.doGet(uri, &AddressInfo{})
+	result, err := c.doGet(ctx, uri, &AddressInfo{})
 	if err != nil {
 		return nil, err
 	}
@@ -93,24 +123,52 @@ func (c *HTTPClient) GetAddressInfo(address Address) (*AddressInfo, error) {
 }
 
 func (c *HTTPClient) GetScriptHashInfo(hash ScriptHash) (*ScriptHashInfo, error) {
+	return c.GetScriptHashInfoCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetScriptHashInfoCtx(ctx context.Context, hash ScriptHash) (*ScriptHashInfo, error) {
 	uri := fmt.Sprintf("/scripthash/%s", hash)
-	result, err := c.doGet(uri, &ScriptHashInfo{})
+	result, err := c.doGet(ctx, uri, &ScriptHashInfo{})
 	if err != nil {
 		return nil, err
 	}
 	return result.(*ScriptHashInfo), nil
 }
 
+// SearchAddressPrefix returns up to 10 known addresses starting with
+// prefix, for autocompletion.
+func (c *HTTPClient) SearchAddressPrefix(prefix string) ([]Address, error) {
+	return c.SearchAddressPrefixCtx(context.Background(), prefix)
+}
+
+func (c *HTTPClient) SearchAddressPrefixCtx(ctx context.Context, prefix string) ([]Address, error) {
+	uri := fmt.Sprintf("/address-prefix/%s", url.PathEscape(prefix))
+	result, err := c.doGetBody(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]Address, 0)
+	if err := decode(uri, result, &addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
 func (c *HTTPClient) GetAddressTransactions(address Address) ([]*Transaction, error) {
+	return c.GetAddressTransactionsCtx(context.Background(), address)
+}
+
+func (c *HTTPClient) GetAddressTransactionsCtx(ctx context.Context, address Address) ([]*Transaction, error) {
 	uri := fmt.Sprintf("/address/%s/txs", address)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -118,15 +176,19 @@ func (c *HTTPClient) GetAddressTransactions(address Address) ([]*Transaction, er
 }
 
 func (c *HTTPClient) GetScriptHashTransactions(hash ScriptHash) ([]*Transaction, error) {
+	return c.GetScriptHashTransactionsCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetScriptHashTransactionsCtx(ctx context.Context, hash ScriptHash) ([]*Transaction, error) {
 	uri := fmt.Sprintf("/scripthash/%s/txs", hash)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -134,15 +196,22 @@ func (c *HTTPClient) GetScriptHashTransactions(hash ScriptHash) ([]*Transaction,
 }
 
 func (c *HTTPClient) GetAddressTransactionsLatest(address Address, lastTxID TxID) ([]*Transaction, error) {
-	uri := fmt.Sprintf("/address/%s/txs/chain/%s", address, lastTxID)
-	result, err := c.doGetBody(uri)
+	return c.GetAddressTransactionsLatestCtx(context.Background(), address, lastTxID)
+}
+
+func (c *HTTPClient) GetAddressTransactionsLatestCtx(ctx context.Context, address Address, lastTxID TxID) ([]*Transaction, error) {
+	uri := fmt.Sprintf("/address/%s/txs/chain", address)
+	if lastTxID != "" {
+		uri = fmt.Sprintf("%s/%s", uri, lastTxID)
+	}
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -150,15 +219,22 @@ func (c *HTTPClient) GetAddressTransactionsLatest(address Address, lastTxID TxID
 }
 
 func (c *HTTPClient) GetScriptHashTransactionsLatest(address Address, lastTxID TxID) ([]*Transaction, error) {
-	uri := fmt.Sprintf("/scripthash/%s/txs/chain/%s", address, lastTxID)
-	result, err := c.doGetBody(uri)
+	return c.GetScriptHashTransactionsLatestCtx(context.Background(), address, lastTxID)
+}
+
+func (c *HTTPClient) GetScriptHashTransactionsLatestCtx(ctx context.Context, address Address, lastTxID TxID) ([]*Transaction, error) {
+	uri := fmt.Sprintf("/scripthash/%s/txs/chain", address)
+	if lastTxID != "" {
+		uri = fmt.Sprintf("%s/%s", uri, lastTxID)
+	}
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -166,15 +242,19 @@ func (c *HTTPClient) GetScriptHashTransactionsLatest(address Address, lastTxID T
 }
 
 func (c *HTTPClient) GetAddressTransactionsInMemPool(address Address) ([]*Transaction, error) {
+	return c.GetAddressTransactionsInMemPoolCtx(context.Background(), address)
+}
+
+func (c *HTTPClient) GetAddressTransactionsInMemPoolCtx(ctx context.Context, address Address) ([]*Transaction, error) {
 	uri := fmt.Sprintf("/address/%s/txs/mempool", address)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -182,15 +262,19 @@ func (c *HTTPClient) GetAddressTransactionsInMemPool(address Address) ([]*Transa
 }
 
 func (c *HTTPClient) GetScriptHashTransactionsInMemPool(hash ScriptHash) ([]*Transaction, error) {
+	return c.GetScriptHashTransactionsInMemPoolCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetScriptHashTransactionsInMemPoolCtx(ctx context.Context, hash ScriptHash) ([]*Transaction, error) {
 	uri := fmt.Sprintf("/scripthash/%s/txs/mempool", hash)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -198,14 +282,18 @@ func (c *HTTPClient) GetScriptHashTransactionsInMemPool(hash ScriptHash) ([]*Tra
 }
 
 func (c *HTTPClient) GetAddressUnspentTxOutputs(address Address) ([]*UnspentTransactionOutput, error) {
+	return c.GetAddressUnspentTxOutputsCtx(context.Background(), address)
+}
+
+func (c *HTTPClient) GetAddressUnspentTxOutputsCtx(ctx context.Context, address Address) ([]*UnspentTransactionOutput, error) {
 	uri := fmt.Sprintf("/address/%s/utxo", address)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	unspentTxOutputs := make([]*UnspentTransactionOutput, 0)
-	err = json.Unmarshal(result, &unspentTxOutputs)
+	err = decode(uri, result, &unspentTxOutputs)
 	if err != nil {
 		return nil, err
 	}
@@ -213,14 +301,18 @@ func (c *HTTPClient) GetAddressUnspentTxOutputs(address Address) ([]*UnspentTran
 }
 
 func (c *HTTPClient) GetScriptHashUnspentTxOutputs(hash ScriptHash) ([]*UnspentTransactionOutput, error) {
+	return c.GetScriptHashUnspentTxOutputsCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetScriptHashUnspentTxOutputsCtx(ctx context.Context, hash ScriptHash) ([]*UnspentTransactionOutput, error) {
 	uri := fmt.Sprintf("/scripthash/%s/utxo", hash)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	unspentTxOutputs := make([]*UnspentTransactionOutput, 0)
-	err = json.Unmarshal(result, &unspentTxOutputs)
+	err = decode(uri, result, &unspentTxOutputs)
 	if err != nil {
 		return nil, err
 	}
@@ -228,8 +320,12 @@ func (c *HTTPClient) GetScriptHashUnspentTxOutputs(hash ScriptHash) ([]*UnspentT
 }
 
 func (c *HTTPClient) GetBlock(hash BlockHash) (*Block, error) {
+	return c.GetBlockCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetBlockCtx(ctx context.Context, hash BlockHash) (*Block, error) {
 	uri := fmt.Sprintf("/block/%s", hash)
-	result, err := c.doGet(uri, &Block{})
+	result, err := c.doGet(ctx, uri, &Block{})
 	if err != nil {
 		return nil, err
 	}
@@ -237,23 +333,57 @@ func (c *HTTPClient) GetBlock(hash BlockHash) (*Block, error) {
 }
 
 func (c *HTTPClient) GetBlockStatus(hash BlockHash) (*BlockStatus, error) {
+	return c.GetBlockStatusCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetBlockStatusCtx(ctx context.Context, hash BlockHash) (*BlockStatus, error) {
 	uri := fmt.Sprintf("/block/%s/status", hash)
-	result, err := c.doGet(uri, &BlockStatus{})
+	result, err := c.doGet(ctx, uri, &BlockStatus{})
 	if err != nil {
 		return nil, err
 	}
 	return result.(*BlockStatus), nil
 }
 
+// GetBlockHeader returns the serialized 80-byte header; see
+// BlockHeaderHex.Decode and GetVerifiedBlockHeader.
+func (c *HTTPClient) GetBlockHeader(hash BlockHash) (BlockHeaderHex, error) {
+	return c.GetBlockHeaderCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetBlockHeaderCtx(ctx context.Context, hash BlockHash) (BlockHeaderHex, error) {
+	uri := fmt.Sprintf("/block/%s/header", hash)
+	result, err := c.doGetBody(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	return BlockHeaderHex(result), nil
+}
+
+// GetRawBlock streams the serialized block from /block/:hash/raw. The
+// caller must close the returned body; wire.NewBlockReader parses it.
+func (c *HTTPClient) GetRawBlock(hash BlockHash) (io.ReadCloser, error) {
+	return c.GetRawBlockCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetRawBlockCtx(ctx context.Context, hash BlockHash) (io.ReadCloser, error) {
+	uri := fmt.Sprintf("/block/%s/raw", hash)
+	return c.doGetStream(ctx, uri)
+}
+
 func (c *HTTPClient) GetBlockTransactions(hash BlockHash, startIndex int32) ([]*Transaction, error) {
+	return c.GetBlockTransactionsCtx(context.Background(), hash, startIndex)
+}
+
+func (c *HTTPClient) GetBlockTransactionsCtx(ctx context.Context, hash BlockHash, startIndex int32) ([]*Transaction, error) {
 	uri := fmt.Sprintf("/block/%s/txs/%d", hash, startIndex)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	transactions := make([]*Transaction, 0)
-	err = json.Unmarshal(result, &transactions)
+	err = decode(uri, result, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -261,14 +391,18 @@ func (c *HTTPClient) GetBlockTransactions(hash BlockHash, startIndex int32) ([]*
 }
 
 func (c *HTTPClient) GetBlockTxIDs(hash BlockHash) ([]TxID, error) {
+	return c.GetBlockTxIDsCtx(context.Background(), hash)
+}
+
+func (c *HTTPClient) GetBlockTxIDsCtx(ctx context.Context, hash BlockHash) ([]TxID, error) {
 	uri := fmt.Sprintf("/block/%s/txids", hash)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	txIds := make([]TxID, 0)
-	err = json.Unmarshal(result, &txIds)
+	err = decode(uri, result, &txIds)
 	if err != nil {
 		return nil, err
 	}
@@ -276,8 +410,12 @@ func (c *HTTPClient) GetBlockTxIDs(hash BlockHash) ([]TxID, error) {
 }
 
 func (c *HTTPClient) GetBlockTxID(hash BlockHash, index int32) (TxID, error) {
+	return c.GetBlockTxIDCtx(context.Background(), hash, index)
+}
+
+func (c *HTTPClient) GetBlockTxIDCtx(ctx context.Context, hash BlockHash, index int32) (TxID, error) {
 	uri := fmt.Sprintf("/block/%s/txid/%d", hash, index)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return "", err
 	}
@@ -285,8 +423,12 @@ func (c *HTTPClient) GetBlockTxID(hash BlockHash, index int32) (TxID, error) {
 }
 
 func (c *HTTPClient) GetBlockHash(height BlockHeight) (BlockHash, error) {
+	return c.GetBlockHashCtx(context.Background(), height)
+}
+
+func (c *HTTPClient) GetBlockHashCtx(ctx context.Context, height BlockHeight) (BlockHash, error) {
 	uri := fmt.Sprintf("/block-height/%d", height)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return "", err
 	}
@@ -294,15 +436,19 @@ func (c *HTTPClient) GetBlockHash(height BlockHeight) (BlockHash, error) {
 }
 
 func (c *HTTPClient) GetBlocks(height BlockHeight) (Blocks, error) {
+	return c.GetBlocksCtx(context.Background(), height)
+}
+
+func (c *HTTPClient) GetBlocksCtx(ctx context.Context, height BlockHeight) (Blocks, error) {
 	uri := fmt.Sprintf("/blocks/%d", height)
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 
 	if err != nil {
 		return nil, err
 	}
 
 	blocks := make(Blocks, 0)
-	err = json.Unmarshal(result, &blocks)
+	err = decode(uri, result, &blocks)
 	if err != nil {
 		return nil, err
 	}
@@ -311,21 +457,29 @@ func (c *HTTPClient) GetBlocks(height BlockHeight) (Blocks, error) {
 }
 
 func (c *HTTPClient) GetLastBlockHeight() (BlockHeight, error) {
+	return c.GetLastBlockHeightCtx(context.Background())
+}
+
+func (c *HTTPClient) GetLastBlockHeightCtx(ctx context.Context) (BlockHeight, error) {
 	uri := fmt.Sprintf("/blocks/tip/height")
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return BlockHeight(0), err
 	}
 	blockHeightInt, err := strconv.Atoi(string(result))
 	if err != nil {
-		return BlockHeight(0), err
+		return BlockHeight(0), &DecodeError{Path: uri, Body: string(result), Err: err}
 	}
 	return BlockHeight(blockHeightInt), nil
 }
 
 func (c *HTTPClient) GetLastBlockHash() (BlockHash, error) {
+	return c.GetLastBlockHashCtx(context.Background())
+}
+
+func (c *HTTPClient) GetLastBlockHashCtx(ctx context.Context) (BlockHash, error) {
 	uri := fmt.Sprintf("/blocks/tip/hash")
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return BlockHash(""), err
 	}
@@ -333,8 +487,12 @@ func (c *HTTPClient) GetLastBlockHash() (BlockHash, error) {
 }
 
 func (c *HTTPClient) GetMemPoolStatistics() (*MemPoolStatistics, error) {
+	return c.GetMemPoolStatisticsCtx(context.Background())
+}
+
+func (c *HTTPClient) GetMemPoolStatisticsCtx(ctx context.Context) (*MemPoolStatistics, error) {
 	uri := fmt.Sprintf("/mempool")
-	result, err := c.doGet(uri, &MemPoolStatistics{})
+	result, err := c.doGet(ctx, uri, &MemPoolStatistics{})
 	if err != nil {
 		return nil, err
 	}
@@ -342,14 +500,18 @@ func (c *HTTPClient) GetMemPoolStatistics() (*MemPoolStatistics, error) {
 }
 
 func (c *HTTPClient) GetMemPoolTxIDs() ([]TxID, error) {
+	return c.GetMemPoolTxIDsCtx(context.Background())
+}
+
+func (c *HTTPClient) GetMemPoolTxIDsCtx(ctx context.Context) ([]TxID, error) {
 	uri := fmt.Sprintf("/mempool/txids")
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	txIds := make([]TxID, 0)
-	err = json.Unmarshal(result, &txIds)
+	err = decode(uri, result, &txIds)
 	if err != nil {
 		return nil, err
 	}
@@ -357,14 +519,18 @@ func (c *HTTPClient) GetMemPoolTxIDs() ([]TxID, error) {
 }
 
 func (c *HTTPClient) GetMemPoolRecentOverviews() ([]*MemPoolOverviewData, error) {
+	return c.GetMemPoolRecentOverviewsCtx(context.Background())
+}
+
+func (c *HTTPClient) GetMemPoolRecentOverviewsCtx(ctx context.Context) ([]*MemPoolOverviewData, error) {
 	uri := fmt.Sprintf("/mempool/recent")
-	result, err := c.doGetBody(uri)
+	result, err := c.doGetBody(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
 
 	overviews := make([]*MemPoolOverviewData, 0)
-	err = json.Unmarshal(result, &overviews)
+	err = decode(uri, result, &overviews)
 	if err != nil {
 		return nil, err
 	}
@@ -372,38 +538,137 @@ func (c *HTTPClient) GetMemPoolRecentOverviews() ([]*MemPoolOverviewData, error)
 }
 
 func (c *HTTPClient) GetFeeEstimates() (*FeeEstimates, error) {
+	return c.GetFeeEstimatesCtx(context.Background())
+}
+
+func (c *HTTPClient) GetFeeEstimatesCtx(ctx context.Context) (*FeeEstimates, error) {
 	uri := fmt.Sprintf("/fee-estimates")
-	result, err := c.doGet(uri, &FeeEstimates{})
+	result, err := c.doGet(ctx, uri, &FeeEstimates{})
 	if err != nil {
 		return nil, err
 	}
 	return result.(*FeeEstimates), nil
 }
 
+func (c *HTTPClient) BroadcastTransaction(txHex TxHex) (TxID, error) {
+	return c.BroadcastTransactionCtx(context.Background(), txHex)
+}
+
+func (c *HTTPClient) BroadcastTransactionCtx(ctx context.Context, txHex TxHex) (TxID, error) {
+	uri := fmt.Sprintf("/tx")
+	result, err := c.doPostBody(ctx, uri, string(txHex))
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+			return "", newBroadcastError(apiErr)
+		}
+		return "", err
+	}
+	return TxID(strings.TrimSpace(string(result))), nil
+}
+
+func (c *HTTPClient) doGet(ctx context.Context, uri string, entity interface{}) (interface{}, error) {
+	body, err := c.doGetBody(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
 
+	if len(body) == 0 {
+		return entity, nil
+	}
+	if err := decode(uri, body, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
 
-func (c *HTTPClient) doGet(uri string, entity interface{}) (interface{}, error) {
-	resp, err := c.Client.R().SetResult(entity).
-		Get(uri)
+func (c *HTTPClient) doGetBody(ctx context.Context, uri string) ([]byte, error) {
+	resp, err := c.doGetResponse(ctx, uri, nil)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("conn err: %s", err.Error()))
+		return nil, err
 	}
+	return resp.Body(), nil
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, errors.New(fmt.Sprintf("request err: %s", string(resp.Body())))
+// doGetResponse is doGetBody with query parameters, for callers that also
+// need the response headers.
+func (c *HTTPClient) doGetResponse(ctx context.Context, uri string, query url.Values) (*resty.Response, error) {
+	var resp *resty.Response
+	err := c.retry.do(ctx, func() error {
+		var err error
+		resp, err = c.Client.R().SetContext(ctx).SetQueryParamsFromValues(query).Get(uri)
+		if err != nil {
+			return connError(ctx, uri, err)
+		}
+
+		if !resp.IsSuccess() {
+			return newAPIError(uri, resp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return resp, nil
+}
 
-	return resp.Result(), nil
+// maxErrorBodyLen bounds how much of a failed streaming response is kept
+// in the APIError.
+const maxErrorBodyLen = 64 << 10
+
+// doGetStream is doGetBody for large responses: retries cover the request
+// up to the response headers, and the body is handed over unread.
+func (c *HTTPClient) doGetStream(ctx context.Context, uri string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := c.retry.do(ctx, func() error {
+		resp, err := c.Client.R().SetContext(ctx).SetDoNotParseResponse(true).Get(uri)
+		if err != nil {
+			return connError(ctx, uri, err)
+		}
+
+		if !resp.IsSuccess() {
+			defer resp.RawBody().Close()
+			errBody, _ := ioutil.ReadAll(io.LimitReader(resp.RawBody(), maxErrorBodyLen))
+			return &APIError{StatusCode: resp.StatusCode(), Path: uri, Body: string(errBody), Header: resp.Header()}
+		}
+		body = resp.RawBody()
+		return nil
+	})
+	return body, err
 }
 
-func (c *HTTPClient) doGetBody(uri string) ([]byte, error) {
-	resp, err := c.Client.R().Get(uri)
+func (c *HTTPClient) doPostBody(ctx context.Context, uri string, body string) ([]byte, error) {
+	resp, err := c.Client.R().SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetBody(body).
+		Post(uri)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("conn err: %s", err.Error()))
+		return nil, connError(ctx, uri, err)
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, errors.New(fmt.Sprintf("request err: %s", string(resp.Body())))
+	if !resp.IsSuccess() {
+		return nil, newAPIError(uri, resp)
 	}
 	return resp.Body(), nil
 }
+
+// connError reports a cancelled or expired ctx as such, wrapping ctx.Err() so
+// callers can match it with errors.Is(err, context.Canceled) or
+// errors.Is(err, context.DeadlineExceeded). Anything else is a *TransportError.
+func connError(ctx context.Context, uri string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("request canceled: %w", ctxErr)
+	}
+	return &TransportError{Path: uri, Err: err}
+}
+
+func newAPIError(uri string, resp *resty.Response) *APIError {
+	return &APIError{StatusCode: resp.StatusCode(), Path: uri, Body: string(resp.Body()), Header: resp.Header()}
+}
+
+func decode(uri string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return &DecodeError{Path: uri, Body: string(body), Err: err}
+	}
+	return nil
+}
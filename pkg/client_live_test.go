@@ -0,0 +1,11 @@
+//go:build live
+// +build live
+
+package pkg
+
+// Run the TestHTTPClient_* cases against a synced mainnet electrs with
+//
+//	go test -tags live ./pkg/
+func init() {
+	liveHostUrl = "http://localhost:3000"
+}
@@ -1,18 +1,42 @@
 package pkg
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
-const (
-	hostUrl = "http://localhost:3000"
-)
+// liveHostUrl is set by client_live_test.go when built with -tags live, in
+// which case the TestHTTPClient_* cases run against a synced electrs instead
+// of the canned fixtures.
+var liveHostUrl string
 
 var (
-	client = NewHTTPClient(hostUrl, false)
+	client        *HTTPClient
+	fixtureClient *HTTPClient
 )
 
+func TestMain(m *testing.M) {
+	server := newFixtureServer()
+	fixtureClient = NewHTTPClient(server.URL, false)
+	client = fixtureClient
+	if liveHostUrl != "" {
+		client = NewHTTPClient(liveHostUrl, false)
+	}
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
 func TestHTTPClient_GetTransaction(t *testing.T) {
 	transaction, err := client.GetTransaction("6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899")
 	if err != nil {
@@ -25,6 +49,21 @@ func TestHTTPClient_GetTransaction(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_GetTransaction_Coinbase(t *testing.T) {
+	transaction, err := client.GetTransaction("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	in := transaction.VIn[0]
+	if !in.IsCoinBase || in.VOut != 4294967295 || in.PrevOut != (TransactionOut{}) {
+		t.Errorf("invalid coinbase input %+v", in)
+	}
+	if out := transaction.VOut[0]; out.ScriptPubKeyType != "p2pk" || out.ScriptPubKeyAddress != "" || out.Value != 50*SatoshiPerBTC {
+		t.Errorf("invalid coinbase output %+v", out)
+	}
+}
+
 func TestHTTPClient_GetTransactionStatus(t *testing.T) {
 	txStatus, err := client.GetTransactionStatus("6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899")
 	if err != nil {
@@ -114,6 +153,37 @@ func TestHTTPClient_GetScriptHashInfo(t *testing.T) {
 
 //3c9018e8d5615c306d72397f8f5eef44308c98fb576a88e030c25456b4f3a7ac
 
+func TestHTTPClient_SearchAddressPrefix(t *testing.T) {
+	addresses, err := client.SearchAddressPrefix("152f1mu")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(addresses) == 0 {
+		t.Errorf("invalid address prefix search")
+	}
+	for _, address := range addresses {
+		if !strings.HasPrefix(string(address), "152f1mu") {
+			t.Errorf("unexpected address %s", address)
+		}
+	}
+}
+
+func TestHTTPClient_SearchAddressPrefix_Escaped(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPClient(server.URL, false).SearchAddressPrefix("../tx/ab?c"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if requestURI != "/address-prefix/..%2Ftx%2Fab%3Fc" {
+		t.Errorf("prefix not escaped: %s", requestURI)
+	}
+}
+
 func TestHTTPClient_GetAddressTransactions(t *testing.T) {
 	transactions, err := client.GetAddressTransactions("152f1muMCNa7goXYhYAQC61hxEgGacmncB")
 	if err != nil {
@@ -209,6 +279,47 @@ func TestHTTPClient_GetBlockStatus(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_GetBlockHeader(t *testing.T) {
+	hash := BlockHash("000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506")
+	headerHex, err := client.GetBlockHeader(hash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	header, err := headerHex.Decode()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if header.Hash() != hash || header.MerkleRoot != "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766" {
+		t.Errorf("invalid block header")
+	}
+
+	if _, err := client.GetVerifiedBlockHeader(hash); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestHTTPClient_GetRawBlock(t *testing.T) {
+	body, err := client.GetRawBlock("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer body.Close()
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(raw) != 285 || hex.EncodeToString(raw[:80]) != string(genesisHeader) {
+		t.Errorf("unexpected raw block %x", raw)
+	}
+
+	_, err = fixtureClient.GetRawBlock("0000000000000000000000000000000000000000000000000000000000000000")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrNotFound) || !strings.Contains(apiErr.Body, "fixture not found") {
+		t.Errorf("expected not found APIError with body, got %v", err)
+	}
+}
+
 func TestHTTPClient_GetBlockTransactions(t *testing.T) {
 	hash := BlockHash("00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a")
 	transactions, err := client.GetBlockTransactions(hash, 50)
@@ -354,6 +465,109 @@ func TestHTTPClient_GetFeeEstimates(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_GetTransactionCtx_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
 
+	_, err := NewHTTPClient(server.URL, false).GetTransactionCtx(ctx, "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
 
+func TestHTTPClient_GetLastBlockHeightCtx_DeadlineExceeded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := NewHTTPClient(server.URL, false).GetLastBlockHeightCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestHTTPClient_BroadcastTransaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/tx" || string(body) != "0200" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899"))
+	}))
+	defer server.Close()
+
+	txID, err := NewHTTPClient(server.URL, false).BroadcastTransaction("0200")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if txID != "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899" {
+		t.Errorf("invalid txid")
+	}
+}
+
+func TestHTTPClient_BroadcastTransaction_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 0 < 141"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClient(server.URL, false).BroadcastTransaction("0200")
+	if !errors.Is(err, ErrMinRelayFeeNotMet) {
+		t.Fatalf("expected ErrMinRelayFeeNotMet, got %v", err)
+	}
+	if !errors.Is(err, ErrBadRequest) {
+		t.Errorf("broadcast error should unwrap to the api error")
+	}
+
+	var broadcastErr *BroadcastError
+	if !errors.As(err, &broadcastErr) || broadcastErr.Code != -26 {
+		t.Errorf("unexpected broadcast error %+v", broadcastErr)
+	}
+}
+
+func TestHTTPClient_GetTransaction_NotFound(t *testing.T) {
+	_, err := fixtureClient.GetTransaction(notFoundTxID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHTTPClient_GetTransaction_BadRequest(t *testing.T) {
+	_, err := fixtureClient.GetTransaction("zz")
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest, got %v", err)
+	}
+}
+
+func TestHTTPClient_GetTransaction_Malformed(t *testing.T) {
+	_, err := fixtureClient.GetTransaction(malformedTxID)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+}
+
+func TestHTTPClient_GetBlock_Unavailable(t *testing.T) {
+	_, err := fixtureClient.GetBlock(unavailableHash)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,540 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	electrumClientName      = "electrs-client"
+	electrumProtocolVersion = "1.4"
+)
+
+var ErrElectrumClosed = errors.New("electrum connection closed")
+
+// ElectrumError is a JSON-RPC error returned by the server.
+type ElectrumError struct {
+	Method  string
+	Code    int
+	Message string
+}
+
+func (e *ElectrumError) Error() string {
+	return fmt.Sprintf("electrum err: %s: %d %s", e.Method, e.Code, e.Message)
+}
+
+type ElectrumOption func(*electrumConfig)
+
+type electrumConfig struct {
+	tlsConfig          *tls.Config
+	clientName         string
+	protocolVersion    string
+	reconnectDelay     time.Duration
+	notificationBuffer int
+}
+
+// ElectrumTLS dials with TLS, as on the usual port 50002.
+func ElectrumTLS(config *tls.Config) ElectrumOption {
+	return func(c *electrumConfig) { c.tlsConfig = config }
+}
+
+func ElectrumClientName(name string) ElectrumOption {
+	return func(c *electrumConfig) { c.clientName = name }
+}
+
+func ElectrumProtocolVersion(version string) ElectrumOption {
+	return func(c *electrumConfig) { c.protocolVersion = version }
+}
+
+// ElectrumReconnect redials delay after the connection is lost, until Close,
+// and re-establishes every subscription. A subscription the server rejects
+// on the new connection has its channels closed. Without reconnecting,
+// subscription channels are closed when the connection is lost.
+func ElectrumReconnect(delay time.Duration) ElectrumOption {
+	return func(c *electrumConfig) { c.reconnectDelay = delay }
+}
+
+// ElectrumNotificationBuffer sets the capacity of subscription channels. It
+// is at least 1, so that the latest notification always fits.
+func ElectrumNotificationBuffer(size int) ElectrumOption {
+	return func(c *electrumConfig) { c.notificationBuffer = size }
+}
+
+// ElectrumClient speaks the Electrum protocol, newline-delimited JSON-RPC
+// over TCP or TLS, to electrs. Calls are safe for concurrent use and are
+// multiplexed over a single connection.
+type ElectrumClient struct {
+	ServerSoftware  string
+	ProtocolVersion string
+
+	addr   string
+	config electrumConfig
+	conn   net.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *electrumResponse
+	err     error
+	done    chan struct{}
+
+	subMu          sync.Mutex
+	closed         bool
+	tip            *HeaderNotification
+	headerSubs     []chan *HeaderNotification
+	scriptHashSubs map[ScriptHash]*scriptHashSubscription
+}
+
+type electrumRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type electrumResponse struct {
+	ID     *uint64         `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// DialElectrum connects to addr ("host:port") and negotiates the protocol
+// version with server.version.
+func DialElectrum(ctx context.Context, addr string, opts ...ElectrumOption) (*ElectrumClient, error) {
+	config := electrumConfig{clientName: electrumClientName, protocolVersion: electrumProtocolVersion, notificationBuffer: 16}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.notificationBuffer < 1 {
+		config.notificationBuffer = 1
+	}
+
+	c := &ElectrumClient{addr: addr, config: config, scriptHashSubs: make(map[ScriptHash]*scriptHashSubscription)}
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	if config.reconnectDelay > 0 {
+		go c.reconnectLoop()
+	}
+	return c, nil
+}
+
+func (c *ElectrumClient) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return connError(ctx, c.addr, err)
+	}
+	if c.config.tlsConfig != nil {
+		tlsConn := tls.Client(conn, c.config.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return connError(ctx, c.addr, err)
+		}
+		conn = tlsConn
+	}
+
+	done := make(chan struct{})
+	c.mu.Lock()
+	if c.ctx != nil && c.ctx.Err() != nil {
+		c.mu.Unlock()
+		_ = conn.Close()
+		return fmt.Errorf("request canceled: %w", c.ctx.Err())
+	}
+	c.conn, c.err, c.done = conn, nil, done
+	c.pending = make(map[uint64]chan *electrumResponse)
+	c.mu.Unlock()
+	go c.readLoop(conn, done)
+
+	var version []string
+	if err := c.call(ctx, "server.version", []interface{}{c.config.clientName, c.config.protocolVersion}, &version); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if len(version) != 2 {
+		_ = conn.Close()
+		return fmt.Errorf("electrum err: unexpected server.version result %v", version)
+	}
+	c.mu.Lock()
+	c.ServerSoftware, c.ProtocolVersion = version[0], version[1]
+	c.mu.Unlock()
+	return nil
+}
+
+// Close closes the connection, stops reconnecting and closes every
+// subscription channel.
+func (c *ElectrumClient) Close() error {
+	c.cancel()
+	c.closeSubscriptions()
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	return conn.Close()
+}
+
+// Done is closed when the current connection is lost or closed.
+func (c *ElectrumClient) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+func (c *ElectrumClient) readLoop(conn net.Conn, done chan struct{}) {
+	reader := bufio.NewReader(conn)
+	var err error
+	for {
+		var line []byte
+		if line, err = reader.ReadBytes('\n'); err != nil {
+			break
+		}
+
+		var resp electrumResponse
+		if json.Unmarshal(line, &resp) != nil {
+			continue
+		}
+		if resp.ID == nil {
+			c.handleNotification(&resp)
+			continue
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[*resp.ID]
+		delete(c.pending, *resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- &resp
+		}
+	}
+
+	c.mu.Lock()
+	c.err = &TransportError{Path: c.addr, Err: fmt.Errorf("%w: %s", ErrElectrumClosed, err.Error())}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(done)
+
+	if c.config.reconnectDelay <= 0 {
+		c.closeSubscriptions()
+	}
+}
+
+// reconnectLoop redials after every connection loss and resubscribes.
+func (c *ElectrumClient) reconnectLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.Done():
+		}
+
+		for {
+			timer := time.NewTimer(c.config.reconnectDelay)
+			select {
+			case <-c.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if c.connect(c.ctx) == nil {
+				break
+			}
+		}
+		// Rejected subscriptions are closed by resubscribe; any other
+		// failure means the new connection dropped as well, which the next
+		// iteration picks up.
+		_ = c.resubscribe(c.ctx)
+	}
+}
+
+func (c *ElectrumClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *electrumResponse, 1)
+	c.pending[id] = ch
+	conn := c.conn
+	c.mu.Unlock()
+
+	data, err := json.Marshal(&electrumRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		c.forget(id)
+		return err
+	}
+	c.writeMu.Lock()
+	// The deadline is set on every call, so that one left by an earlier
+	// call cannot fail this write.
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetWriteDeadline(deadline)
+	_, err = conn.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(id)
+		return connError(ctx, c.addr, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.err
+		}
+		return decodeElectrumResponse(method, resp, result)
+	case <-ctx.Done():
+		c.forget(id)
+		return fmt.Errorf("request canceled: %w", ctx.Err())
+	}
+}
+
+func (c *ElectrumClient) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func decodeElectrumResponse(method string, resp *electrumResponse, result interface{}) error {
+	if len(resp.Error) > 0 && string(resp.Error) != "null" {
+		electrumErr := &ElectrumError{Method: method}
+		if json.Unmarshal(resp.Error, electrumErr) != nil || electrumErr.Message == "" {
+			var message string
+			if json.Unmarshal(resp.Error, &message) != nil {
+				message = string(resp.Error)
+			}
+			electrumErr.Message = message
+		}
+		return electrumErr
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return &DecodeError{Path: method, Body: string(resp.Result), Err: err}
+	}
+	return nil
+}
+
+func (c *ElectrumClient) Ping() error {
+	return c.PingCtx(context.Background())
+}
+
+func (c *ElectrumClient) PingCtx(ctx context.Context) error {
+	return c.call(ctx, "server.ping", nil, nil)
+}
+
+func (c *ElectrumClient) GetScriptHashBalance(hash ScriptHash) (*ElectrumBalance, error) {
+	return c.GetScriptHashBalanceCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) GetScriptHashBalanceCtx(ctx context.Context, hash ScriptHash) (*ElectrumBalance, error) {
+	balance := &ElectrumBalance{}
+	if err := c.call(ctx, "blockchain.scripthash.get_balance", []interface{}{hash}, balance); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+func (c *ElectrumClient) GetScriptHashHistory(hash ScriptHash) ([]*ElectrumHistoryItem, error) {
+	return c.GetScriptHashHistoryCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) GetScriptHashHistoryCtx(ctx context.Context, hash ScriptHash) ([]*ElectrumHistoryItem, error) {
+	history := make([]*ElectrumHistoryItem, 0)
+	if err := c.call(ctx, "blockchain.scripthash.get_history", []interface{}{hash}, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (c *ElectrumClient) GetScriptHashMempool(hash ScriptHash) ([]*ElectrumHistoryItem, error) {
+	return c.GetScriptHashMempoolCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) GetScriptHashMempoolCtx(ctx context.Context, hash ScriptHash) ([]*ElectrumHistoryItem, error) {
+	history := make([]*ElectrumHistoryItem, 0)
+	if err := c.call(ctx, "blockchain.scripthash.get_mempool", []interface{}{hash}, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (c *ElectrumClient) GetScriptHashUnspentTxOutputs(hash ScriptHash) ([]*UnspentTransactionOutput, error) {
+	return c.GetScriptHashUnspentTxOutputsCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) GetScriptHashUnspentTxOutputsCtx(ctx context.Context, hash ScriptHash) ([]*UnspentTransactionOutput, error) {
+	unspent := make([]struct {
+		TxHash TxID        `json:"tx_hash"`
+		TxPos  int32       `json:"tx_pos"`
+		Height BlockHeight `json:"height"`
+		Value  Satoshi     `json:"value"`
+	}, 0)
+	if err := c.call(ctx, "blockchain.scripthash.listunspent", []interface{}{hash}, &unspent); err != nil {
+		return nil, err
+	}
+
+	unspentTxOutputs := make([]*UnspentTransactionOutput, 0, len(unspent))
+	for _, u := range unspent {
+		output := &UnspentTransactionOutput{ID: u.TxHash, VOut: u.TxPos, Value: u.Value}
+		if u.Height > 0 {
+			output.Status = TransactionStatus{Confirmed: true, BlockHeight: u.Height}
+		}
+		unspentTxOutputs = append(unspentTxOutputs, output)
+	}
+	return unspentTxOutputs, nil
+}
+
+func (c *ElectrumClient) GetTransactionHex(txID TxID) (TxHex, error) {
+	return c.GetTransactionHexCtx(context.Background(), txID)
+}
+
+func (c *ElectrumClient) GetTransactionHexCtx(ctx context.Context, txID TxID) (TxHex, error) {
+	var txHex TxHex
+	if err := c.call(ctx, "blockchain.transaction.get", []interface{}{txID, false}, &txHex); err != nil {
+		return "", err
+	}
+	return txHex, nil
+}
+
+func (c *ElectrumClient) BroadcastTransaction(txHex TxHex) (TxID, error) {
+	return c.BroadcastTransactionCtx(context.Background(), txHex)
+}
+
+// BroadcastTransactionCtx reports rejections as *BroadcastError, like
+// HTTPClient.BroadcastTransactionCtx.
+func (c *ElectrumClient) BroadcastTransactionCtx(ctx context.Context, txHex TxHex) (TxID, error) {
+	var txID TxID
+	if err := c.call(ctx, "blockchain.transaction.broadcast", []interface{}{txHex}, &txID); err != nil {
+		var electrumErr *ElectrumError
+		if errors.As(err, &electrumErr) {
+			return "", parseRejectMessage(electrumErr.Message)
+		}
+		return "", err
+	}
+	return txID, nil
+}
+
+func (c *ElectrumClient) GetTransactionMerkleProof(txID TxID, height BlockHeight) (*TransactionMerkleProof, error) {
+	return c.GetTransactionMerkleProofCtx(context.Background(), txID, height)
+}
+
+func (c *ElectrumClient) GetTransactionMerkleProofCtx(ctx context.Context, txID TxID, height BlockHeight) (*TransactionMerkleProof, error) {
+	proof := &TransactionMerkleProof{}
+	if err := c.call(ctx, "blockchain.transaction.get_merkle", []interface{}{txID, height}, proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+func (c *ElectrumClient) GetTxIDFromPos(height BlockHeight, pos int32) (TxID, error) {
+	return c.GetTxIDFromPosCtx(context.Background(), height, pos)
+}
+
+func (c *ElectrumClient) GetTxIDFromPosCtx(ctx context.Context, height BlockHeight, pos int32) (TxID, error) {
+	var txID TxID
+	if err := c.call(ctx, "blockchain.transaction.id_from_pos", []interface{}{height, pos, false}, &txID); err != nil {
+		return "", err
+	}
+	return txID, nil
+}
+
+func (c *ElectrumClient) GetBlockHeader(height BlockHeight) (BlockHeaderHex, error) {
+	return c.GetBlockHeaderCtx(context.Background(), height)
+}
+
+func (c *ElectrumClient) GetBlockHeaderCtx(ctx context.Context, height BlockHeight) (BlockHeaderHex, error) {
+	var header BlockHeaderHex
+	if err := c.call(ctx, "blockchain.block.header", []interface{}{height}, &header); err != nil {
+		return "", err
+	}
+	return header, nil
+}
+
+// GetBlockHeaders returns up to count consecutive headers starting at
+// startHeight. The server may return fewer, capped at its own maximum.
+func (c *ElectrumClient) GetBlockHeaders(startHeight BlockHeight, count int) ([]BlockHeaderHex, error) {
+	return c.GetBlockHeadersCtx(context.Background(), startHeight, count)
+}
+
+func (c *ElectrumClient) GetBlockHeadersCtx(ctx context.Context, startHeight BlockHeight, count int) ([]BlockHeaderHex, error) {
+	result := struct {
+		Count int    `json:"count"`
+		Hex   string `json:"hex"`
+		Max   int    `json:"max"`
+	}{}
+	if err := c.call(ctx, "blockchain.block.headers", []interface{}{startHeight, count}, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Hex) != result.Count*blockHeaderHexLen {
+		return nil, &DecodeError{Path: "blockchain.block.headers", Body: result.Hex, Err: fmt.Errorf("expected %d headers", result.Count)}
+	}
+
+	headers := make([]BlockHeaderHex, 0, result.Count)
+	for i := 0; i < result.Count; i++ {
+		headers = append(headers, BlockHeaderHex(result.Hex[i*blockHeaderHexLen:(i+1)*blockHeaderHexLen]))
+	}
+	return headers, nil
+}
+
+// EstimateFee returns the fee rate in BTC/kB to confirm within blocks, or
+// -1 when the server has no estimate. FeeRateFromBTCPerKB converts it.
+func (c *ElectrumClient) EstimateFee(blocks int) (float64, error) {
+	return c.EstimateFeeCtx(context.Background(), blocks)
+}
+
+func (c *ElectrumClient) EstimateFeeCtx(ctx context.Context, blocks int) (float64, error) {
+	var fee float64
+	if err := c.call(ctx, "blockchain.estimatefee", []interface{}{blocks}, &fee); err != nil {
+		return 0, err
+	}
+	return fee, nil
+}
+
+// RelayFee returns the minimum relay fee rate in BTC/kB.
+func (c *ElectrumClient) RelayFee() (float64, error) {
+	return c.RelayFeeCtx(context.Background())
+}
+
+func (c *ElectrumClient) RelayFeeCtx(ctx context.Context) (float64, error) {
+	var fee float64
+	if err := c.call(ctx, "blockchain.relayfee", nil, &fee); err != nil {
+		return 0, err
+	}
+	return fee, nil
+}
+
+// GetFeeHistogram returns the same histogram as
+// MemPoolStatistics.FeeHistogram.
+func (c *ElectrumClient) GetFeeHistogram() (FeeHistogram, error) {
+	return c.GetFeeHistogramCtx(context.Background())
+}
+
+func (c *ElectrumClient) GetFeeHistogramCtx(ctx context.Context) (FeeHistogram, error) {
+	histogram := make(FeeHistogram, 0)
+	if err := c.call(ctx, "mempool.get_fee_histogram", nil, &histogram); err != nil {
+		return nil, err
+	}
+	return histogram, nil
+}
@@ -0,0 +1,321 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrStatusMismatch = errors.New("scripthash status does not match history")
+
+type scriptHashSubscription struct {
+	status string
+	subs   []chan ScriptHashStatus
+}
+
+// SubscribeHeaders returns the current tip and a channel receiving every new
+// tip the server announces. Like the server, the channel only guarantees the
+// latest tip: when the consumer falls behind, older notifications are
+// dropped, so use the height to fetch any blocks in between.
+func (c *ElectrumClient) SubscribeHeaders() (*HeaderNotification, <-chan *HeaderNotification, error) {
+	return c.SubscribeHeadersCtx(context.Background())
+}
+
+func (c *ElectrumClient) SubscribeHeadersCtx(ctx context.Context) (*HeaderNotification, <-chan *HeaderNotification, error) {
+	ch := make(chan *HeaderNotification, c.config.notificationBuffer)
+	c.subMu.Lock()
+	if c.closed {
+		c.subMu.Unlock()
+		return nil, nil, &TransportError{Path: c.addr, Err: ErrElectrumClosed}
+	}
+	c.headerSubs = append(c.headerSubs, ch)
+	c.subMu.Unlock()
+
+	tip := &HeaderNotification{}
+	if err := c.call(ctx, "blockchain.headers.subscribe", nil, tip); err != nil {
+		c.removeHeaderSubscription(ch)
+		return nil, nil, err
+	}
+	c.subMu.Lock()
+	if c.tip == nil || tip.Height >= c.tip.Height {
+		c.tip = tip
+	}
+	c.subMu.Unlock()
+	return tip, ch, nil
+}
+
+// SubscribeScriptHash returns the current status of hash, empty when it has
+// no history, and a channel receiving every status change. Statuses can be
+// checked with VerifyScriptHashStatus. As with headers only the latest
+// status per channel is guaranteed to be delivered.
+func (c *ElectrumClient) SubscribeScriptHash(hash ScriptHash) (string, <-chan ScriptHashStatus, error) {
+	return c.SubscribeScriptHashCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) SubscribeScriptHashCtx(ctx context.Context, hash ScriptHash) (string, <-chan ScriptHashStatus, error) {
+	ch := make(chan ScriptHashStatus, c.config.notificationBuffer)
+	c.subMu.Lock()
+	if c.closed {
+		c.subMu.Unlock()
+		return "", nil, &TransportError{Path: c.addr, Err: ErrElectrumClosed}
+	}
+	sub, ok := c.scriptHashSubs[hash]
+	if !ok {
+		sub = &scriptHashSubscription{}
+		c.scriptHashSubs[hash] = sub
+	}
+	sub.subs = append(sub.subs, ch)
+	c.subMu.Unlock()
+
+	var status *string
+	if err := c.call(ctx, "blockchain.scripthash.subscribe", []interface{}{hash}, &status); err != nil {
+		c.removeScriptHashSubscription(hash, ch)
+		return "", nil, err
+	}
+	c.subMu.Lock()
+	sub.status = stringValue(status)
+	c.subMu.Unlock()
+	return stringValue(status), ch, nil
+}
+
+// UnsubscribeScriptHash closes every channel subscribed to hash.
+func (c *ElectrumClient) UnsubscribeScriptHash(hash ScriptHash) error {
+	return c.UnsubscribeScriptHashCtx(context.Background(), hash)
+}
+
+func (c *ElectrumClient) UnsubscribeScriptHashCtx(ctx context.Context, hash ScriptHash) error {
+	if !c.closeScriptHashSubscription(hash) {
+		return nil
+	}
+	return c.call(ctx, "blockchain.scripthash.unsubscribe", []interface{}{hash}, nil)
+}
+
+// VerifyScriptHashStatus fetches the history of hash and checks that it
+// hashes to status, returning the history on success.
+func (c *ElectrumClient) VerifyScriptHashStatus(hash ScriptHash, status string) ([]*ElectrumHistoryItem, error) {
+	return c.VerifyScriptHashStatusCtx(context.Background(), hash, status)
+}
+
+func (c *ElectrumClient) VerifyScriptHashStatusCtx(ctx context.Context, hash ScriptHash, status string) ([]*ElectrumHistoryItem, error) {
+	history, err := c.GetScriptHashHistoryCtx(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if computed := ElectrumStatus(history); computed != status {
+		return nil, fmt.Errorf("%w: %s: expected %s, computed %s", ErrStatusMismatch, hash, status, computed)
+	}
+	return history, nil
+}
+
+// ElectrumStatus computes the Electrum status of a scripthash history: the
+// hex sha256 of "tx_hash:height:" concatenated over the history in server
+// order, or empty for no history.
+func ElectrumStatus(history []*ElectrumHistoryItem) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, item := range history {
+		fmt.Fprintf(&b, "%s:%d:", item.TxHash, item.Height)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ElectrumClient) handleNotification(resp *electrumResponse) {
+	switch resp.Method {
+	case "blockchain.headers.subscribe":
+		var params []*HeaderNotification
+		if json.Unmarshal(resp.Params, &params) != nil || len(params) == 0 || params[0] == nil {
+			return
+		}
+		c.notifyHeader(params[0])
+	case "blockchain.scripthash.subscribe":
+		var params []*string
+		if json.Unmarshal(resp.Params, &params) != nil || len(params) < 2 || params[0] == nil {
+			return
+		}
+		c.notifyScriptHash(ScriptHash(*params[0]), stringValue(params[1]))
+	}
+}
+
+func (c *ElectrumClient) notifyHeader(tip *HeaderNotification) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if c.closed {
+		return
+	}
+	c.tip = tip
+	for _, ch := range c.headerSubs {
+		for !offerHeader(ch, tip) {
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	}
+}
+
+func (c *ElectrumClient) notifyScriptHash(hash ScriptHash, status string) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	sub, ok := c.scriptHashSubs[hash]
+	if c.closed || !ok {
+		return
+	}
+	sub.status = status
+	notification := ScriptHashStatus{ScriptHash: hash, Status: status}
+	for _, ch := range sub.subs {
+		for !offerScriptHashStatus(ch, notification) {
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	}
+}
+
+// resubscribe re-establishes every subscription on a new connection and
+// reports whatever changed while disconnected. The channels of a
+// subscription the server rejects are closed, so that consumers do not wait
+// on them forever; other errors are returned.
+func (c *ElectrumClient) resubscribe(ctx context.Context) error {
+	c.subMu.Lock()
+	headers := len(c.headerSubs) > 0
+	lastTip := c.tip
+	statuses := make(map[ScriptHash]string, len(c.scriptHashSubs))
+	for hash, sub := range c.scriptHashSubs {
+		statuses[hash] = sub.status
+	}
+	c.subMu.Unlock()
+
+	var electrumErr *ElectrumError
+	if headers {
+		tip := &HeaderNotification{}
+		err := c.call(ctx, "blockchain.headers.subscribe", nil, tip)
+		switch {
+		case errors.As(err, &electrumErr):
+			c.closeHeaderSubscriptions()
+		case err != nil:
+			return err
+		case lastTip == nil || *tip != *lastTip:
+			c.notifyHeader(tip)
+		}
+	}
+	for hash, last := range statuses {
+		var status *string
+		err := c.call(ctx, "blockchain.scripthash.subscribe", []interface{}{hash}, &status)
+		switch {
+		case errors.As(err, &electrumErr):
+			c.closeScriptHashSubscription(hash)
+		case err != nil:
+			return err
+		case stringValue(status) != last:
+			c.notifyScriptHash(hash, stringValue(status))
+		}
+	}
+	return nil
+}
+
+func (c *ElectrumClient) closeHeaderSubscriptions() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for _, ch := range c.headerSubs {
+		close(ch)
+	}
+	c.headerSubs = nil
+}
+
+// closeScriptHashSubscription closes every channel subscribed to hash and
+// reports whether there was any.
+func (c *ElectrumClient) closeScriptHashSubscription(hash ScriptHash) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	sub, ok := c.scriptHashSubs[hash]
+	if !ok {
+		return false
+	}
+	delete(c.scriptHashSubs, hash)
+	for _, ch := range sub.subs {
+		close(ch)
+	}
+	return true
+}
+
+func (c *ElectrumClient) removeHeaderSubscription(ch chan *HeaderNotification) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for i, sub := range c.headerSubs {
+		if sub == ch {
+			c.headerSubs = append(c.headerSubs[:i], c.headerSubs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *ElectrumClient) removeScriptHashSubscription(hash ScriptHash, ch chan ScriptHashStatus) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	sub, ok := c.scriptHashSubs[hash]
+	if !ok {
+		return
+	}
+	for i, s := range sub.subs {
+		if s == ch {
+			sub.subs = append(sub.subs[:i], sub.subs[i+1:]...)
+			break
+		}
+	}
+	if len(sub.subs) == 0 {
+		delete(c.scriptHashSubs, hash)
+	}
+}
+
+func (c *ElectrumClient) closeSubscriptions() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, ch := range c.headerSubs {
+		close(ch)
+	}
+	for _, sub := range c.scriptHashSubs {
+		for _, ch := range sub.subs {
+			close(ch)
+		}
+	}
+	c.headerSubs, c.scriptHashSubs = nil, nil
+}
+
+// offerHeader and offerScriptHashStatus send without blocking; on a full
+// channel the caller drops the oldest notification and retries.
+func offerHeader(ch chan *HeaderNotification, tip *HeaderNotification) bool {
+	select {
+	case ch <- tip:
+		return true
+	default:
+		return false
+	}
+}
+
+func offerScriptHashStatus(ch chan ScriptHashStatus, status ScriptHashStatus) bool {
+	select {
+	case ch <- status:
+		return true
+	default:
+		return false
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,253 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var electrumHistory = []*ElectrumHistoryItem{
+	{TxHash: "a9f5d2a4b0c8b2d0e1f3a6c1b7e9d5f2a4b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4", Height: 200004},
+	{TxHash: "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403", Height: 0},
+}
+
+const electrumHistoryStatus = "bbe9dda3e01ca09062100dda8e985d9bb8aacbe78c7ea012d2b08b8fd80b51ea"
+
+func TestElectrumStatus(t *testing.T) {
+	if status := ElectrumStatus(electrumHistory); status != electrumHistoryStatus {
+		t.Errorf("unexpected status %s", status)
+	}
+	if status := ElectrumStatus(nil); status != "" {
+		t.Errorf("expected empty status for no history, got %s", status)
+	}
+}
+
+func TestElectrumClient_SubscribeHeaders(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	tip, headers, err := c.SubscribeHeaders()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip.Height != 100 {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+
+	s.notify("blockchain.headers.subscribe", map[string]interface{}{"height": 101, "hex": "01"})
+	select {
+	case header := <-headers:
+		if header.Height != 101 || header.Hex != "01" {
+			t.Errorf("unexpected header %+v", header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("header notification not delivered")
+	}
+}
+
+func TestElectrumClient_SubscribeScriptHash(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.scripthash.subscribe", nil)
+	s.result("blockchain.scripthash.unsubscribe", true)
+	s.result("blockchain.scripthash.get_history", electrumHistory)
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	status, statuses, err := c.SubscribeScriptHash(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if status != "" {
+		t.Errorf("expected empty status, got %s", status)
+	}
+
+	s.notify("blockchain.scripthash.subscribe", electrumScriptHash, electrumHistoryStatus)
+	select {
+	case notification := <-statuses:
+		if notification.ScriptHash != electrumScriptHash || notification.Status != electrumHistoryStatus {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+		history, err := c.VerifyScriptHashStatus(notification.ScriptHash, notification.Status)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(history) != 2 {
+			t.Errorf("unexpected history %+v", history)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("status notification not delivered")
+	}
+
+	if _, err := c.VerifyScriptHashStatus(electrumScriptHash, "00"); !errors.Is(err, ErrStatusMismatch) {
+		t.Errorf("expected ErrStatusMismatch, got %v", err)
+	}
+
+	if err := c.UnsubscribeScriptHash(electrumScriptHash); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := <-statuses; ok {
+		t.Errorf("expected channel to be closed after unsubscribe")
+	}
+}
+
+func TestElectrumClient_UnbufferedNotifications(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+	s.result("server.ping", nil)
+
+	for _, size := range []int{0, -1} {
+		c, err := DialElectrum(context.Background(), s.addr(), ElectrumNotificationBuffer(size))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, headers, err := c.SubscribeHeaders()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		s.notify("blockchain.headers.subscribe", map[string]interface{}{"height": 101, "hex": "01"})
+		s.notify("blockchain.headers.subscribe", map[string]interface{}{"height": 102, "hex": "02"})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := c.PingCtx(ctx); err != nil {
+			t.Errorf("buffer %d: unexpected error %v", size, err)
+		}
+		cancel()
+		if header := <-headers; header.Height != 102 {
+			t.Errorf("buffer %d: expected the latest header, got %+v", size, header)
+		}
+		_ = c.Close()
+	}
+}
+
+func TestElectrumClient_Reconnect(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+	s.result("blockchain.scripthash.subscribe", nil)
+
+	c, err := DialElectrum(context.Background(), s.addr(), ElectrumReconnect(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer c.Close()
+
+	_, headers, err := c.SubscribeHeaders()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, statuses, err := c.SubscribeScriptHash(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// While disconnected a block arrives and the scripthash gets history.
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 101, "hex": "01"})
+	s.result("blockchain.scripthash.subscribe", electrumHistoryStatus)
+	s.drop()
+
+	select {
+	case header := <-headers:
+		if header.Height != 101 {
+			t.Errorf("unexpected header %+v", header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("headers subscription not re-established")
+	}
+	select {
+	case notification := <-statuses:
+		if notification.Status != electrumHistoryStatus {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scripthash subscription not re-established")
+	}
+
+	// Notifications on the new connection still reach the old channels.
+	s.notify("blockchain.headers.subscribe", map[string]interface{}{"height": 102, "hex": "02"})
+	select {
+	case header := <-headers:
+		if header.Height != 102 {
+			t.Errorf("unexpected header %+v", header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("header notification not delivered after reconnect")
+	}
+}
+
+func TestElectrumClient_ResubscribeRejected(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+	s.result("blockchain.scripthash.subscribe", nil)
+
+	c, err := DialElectrum(context.Background(), s.addr(), ElectrumReconnect(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer c.Close()
+
+	_, headers, err := c.SubscribeHeaders()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, statuses, err := c.SubscribeScriptHash(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.handle("blockchain.scripthash.subscribe", func(params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("too many subscriptions")
+	})
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 101, "hex": "01"})
+	s.drop()
+
+	select {
+	case _, ok := <-statuses:
+		if ok {
+			t.Errorf("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("rejected subscription not closed")
+	}
+	select {
+	case header := <-headers:
+		if header == nil || header.Height != 101 {
+			t.Errorf("unexpected header %+v", header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("headers subscription not re-established")
+	}
+}
+
+func TestElectrumClient_SubscriptionsClosed(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.headers.subscribe", map[string]interface{}{"height": 100, "hex": "00"})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	_, headers, err := c.SubscribeHeaders()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.drop()
+	select {
+	case _, ok := <-headers:
+		if ok {
+			t.Errorf("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after connection loss")
+	}
+	if _, _, err := c.SubscribeHeaders(); !errors.Is(err, ErrElectrumClosed) {
+		t.Errorf("expected ErrElectrumClosed, got %v", err)
+	}
+}
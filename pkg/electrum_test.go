@@ -0,0 +1,410 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// electrumServer is a local stand-in for the electrs Electrum RPC port.
+// Handlers return the raw JSON result, or an error which is sent back as a
+// JSON-RPC error object.
+type electrumServer struct {
+	listener net.Listener
+	handlers map[string]func(params []json.RawMessage) (interface{}, error)
+
+	mu      sync.Mutex
+	writeMu sync.Mutex
+	conns   []net.Conn
+}
+
+func newElectrumServer(t *testing.T) *electrumServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s := &electrumServer{listener: listener, handlers: make(map[string]func([]json.RawMessage) (interface{}, error))}
+	s.handle("server.version", func(params []json.RawMessage) (interface{}, error) {
+		return []string{"electrs/0.9.0", "1.4"}, nil
+	})
+	go s.serve()
+	return s
+}
+
+func (s *electrumServer) handle(method string, handler func(params []json.RawMessage) (interface{}, error)) {
+	s.mu.Lock()
+	s.handlers[method] = handler
+	s.mu.Unlock()
+}
+
+func (s *electrumServer) result(method string, result interface{}) {
+	s.handle(method, func(params []json.RawMessage) (interface{}, error) { return result, nil })
+}
+
+func (s *electrumServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *electrumServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *electrumServer) serveConn(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &req) != nil {
+			return
+		}
+
+		s.mu.Lock()
+		handler, ok := s.handlers[req.Method]
+		s.mu.Unlock()
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if !ok {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "unknown method " + req.Method}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": 1, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+
+		s.write(conn, resp)
+	}
+}
+
+func (s *electrumServer) write(conn net.Conn, msg interface{}) {
+	data, _ := json.Marshal(msg)
+	s.writeMu.Lock()
+	_, _ = conn.Write(append(data, '\n'))
+	s.writeMu.Unlock()
+}
+
+// notify pushes a notification to every open connection.
+func (s *electrumServer) notify(method string, params ...interface{}) {
+	s.mu.Lock()
+	conns := append([]net.Conn(nil), s.conns...)
+	s.mu.Unlock()
+	for _, conn := range conns {
+		s.write(conn, map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+	}
+}
+
+// drop closes every open connection without stopping the listener.
+func (s *electrumServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *electrumServer) close() {
+	_ = s.listener.Close()
+	s.drop()
+}
+
+func dialTestElectrum(t *testing.T, s *electrumServer) *ElectrumClient {
+	c, err := DialElectrum(context.Background(), s.addr())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return c
+}
+
+const electrumScriptHash ScriptHash = "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"
+
+func TestElectrumClient_Version(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	var requested []string
+	s.handle("server.version", func(params []json.RawMessage) (interface{}, error) {
+		for _, p := range params {
+			var v string
+			_ = json.Unmarshal(p, &v)
+			requested = append(requested, v)
+		}
+		return []string{"electrs/0.9.0", "1.4"}, nil
+	})
+
+	c, err := DialElectrum(context.Background(), s.addr(), ElectrumClientName("wallet"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer c.Close()
+
+	if c.ServerSoftware != "electrs/0.9.0" || c.ProtocolVersion != "1.4" {
+		t.Errorf("unexpected version %s %s", c.ServerSoftware, c.ProtocolVersion)
+	}
+	if strings.Join(requested, " ") != "wallet 1.4" {
+		t.Errorf("unexpected server.version params %v", requested)
+	}
+}
+
+func TestElectrumClient_ScriptHash(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.scripthash.get_balance", map[string]int64{"confirmed": 103873966, "unconfirmed": 23684400})
+	s.result("blockchain.scripthash.get_history", []map[string]interface{}{
+		{"tx_hash": "a9f5d2a4b0c8b2d0e1f3a6c1b7e9d5f2a4b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4", "height": 200004},
+		{"tx_hash": "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403", "height": 0, "fee": 20000},
+	})
+	s.result("blockchain.scripthash.get_mempool", []map[string]interface{}{
+		{"tx_hash": "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403", "height": -1, "fee": 20000},
+	})
+	s.result("blockchain.scripthash.listunspent", []map[string]interface{}{
+		{"tx_hash": "a9f5d2a4b0c8b2d0e1f3a6c1b7e9d5f2a4b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4", "tx_pos": 1, "height": 200004, "value": 103873966},
+		{"tx_hash": "f3e1bf48975b8d6060a9de8884296abb80be618dc00ae3cb2f6cee3085e09403", "tx_pos": 0, "height": 0, "value": 23684400},
+	})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	balance, err := c.GetScriptHashBalance(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if balance.Confirmed != 103873966 || balance.Unconfirmed != 23684400 {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+
+	history, err := c.GetScriptHashHistory(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(history) != 2 || history[0].Height != 200004 || history[1].Fee != 20000 {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	mempool, err := c.GetScriptHashMempool(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(mempool) != 1 || mempool[0].Height != -1 {
+		t.Errorf("unexpected mempool %+v", mempool)
+	}
+
+	unspent, err := c.GetScriptHashUnspentTxOutputs(electrumScriptHash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(unspent) != 2 {
+		t.Fatalf("expected 2 unspent outputs, got %d", len(unspent))
+	}
+	if !unspent[0].Status.Confirmed || unspent[0].Status.BlockHeight != 200004 || unspent[0].VOut != 1 {
+		t.Errorf("unexpected confirmed output %+v", unspent[0])
+	}
+	if unspent[1].Status.Confirmed || unspent[1].Value != 23684400 {
+		t.Errorf("unexpected unconfirmed output %+v", unspent[1])
+	}
+}
+
+func TestElectrumClient_Transaction(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.transaction.get", "0100000001")
+	s.result("blockchain.transaction.get_merkle", map[string]interface{}{
+		"block_height": 450538,
+		"merkle":       []string{"713d6c7e6ce7bbea708d61162231eaa8ecb31c4c5dd84f81c20409a90069cb24"},
+		"pos":          710,
+	})
+	s.result("blockchain.transaction.id_from_pos", "fc12dfcb4723715a456c6984e298e00c479706067da81be969e8085544b0ba08")
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	txHex, err := c.GetTransactionHex("fc12dfcb4723715a456c6984e298e00c479706067da81be969e8085544b0ba08")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if txHex != "0100000001" {
+		t.Errorf("unexpected tx hex %s", txHex)
+	}
+
+	proof, err := c.GetTransactionMerkleProof("fc12dfcb4723715a456c6984e298e00c479706067da81be969e8085544b0ba08", 450538)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if proof.BlockHeight != 450538 || proof.Pos != 710 || len(proof.Merkle) != 1 {
+		t.Errorf("unexpected merkle proof %+v", proof)
+	}
+
+	txID, err := c.GetTxIDFromPos(450538, 710)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if txID != "fc12dfcb4723715a456c6984e298e00c479706067da81be969e8085544b0ba08" {
+		t.Errorf("unexpected txid %s", txID)
+	}
+}
+
+func TestElectrumClient_BroadcastTransaction(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.handle("blockchain.transaction.broadcast", func(params []json.RawMessage) (interface{}, error) {
+		var txHex string
+		_ = json.Unmarshal(params[0], &txHex)
+		if txHex == "00" {
+			return nil, errors.New(`sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 0 < 141"}`)
+		}
+		return "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899", nil
+	})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	txID, err := c.BroadcastTransaction("0100000001")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if txID != "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899" {
+		t.Errorf("unexpected txid %s", txID)
+	}
+
+	_, err = c.BroadcastTransaction("00")
+	if !errors.Is(err, ErrMinRelayFeeNotMet) {
+		t.Errorf("expected ErrMinRelayFeeNotMet, got %v", err)
+	}
+	var broadcastErr *BroadcastError
+	if !errors.As(err, &broadcastErr) || broadcastErr.Code != -26 || broadcastErr.API != nil {
+		t.Errorf("unexpected broadcast error %+v", err)
+	}
+}
+
+func TestElectrumClient_BlockHeaders(t *testing.T) {
+	header := strings.Repeat("00", 80)
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.block.header", header)
+	s.result("blockchain.block.headers", map[string]interface{}{"count": 2, "hex": header + header, "max": 2016})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	h, err := c.GetBlockHeader(0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(h) != header {
+		t.Errorf("unexpected header %s", h)
+	}
+
+	headers, err := c.GetBlockHeaders(0, 2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(headers) != 2 || string(headers[1]) != header {
+		t.Errorf("unexpected headers %v", headers)
+	}
+}
+
+func TestElectrumClient_Fees(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.result("blockchain.estimatefee", 0.00021)
+	s.result("blockchain.relayfee", 0.00001)
+	s.result("mempool.get_fee_histogram", [][]float64{{53.01, 102030}, {38.56, 110000}})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	fee, err := c.EstimateFee(6)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if fee != 0.00021 {
+		t.Errorf("unexpected fee estimate %v", fee)
+	}
+
+	relayFee, err := c.RelayFee()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if relayFee != 0.00001 {
+		t.Errorf("unexpected relay fee %v", relayFee)
+	}
+
+	histogram, err := c.GetFeeHistogram()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(histogram) != 2 || histogram[0] != (FeeBucket{FeeRate: 53.01, VSize: 102030}) {
+		t.Errorf("unexpected fee histogram %v", histogram)
+	}
+}
+
+func TestElectrumClient_Errors(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.handle("blockchain.transaction.get", func(params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("No such mempool or blockchain transaction")
+	})
+	block := make(chan struct{})
+	s.handle("server.ping", func(params []json.RawMessage) (interface{}, error) {
+		<-block
+		return nil, nil
+	})
+	defer close(block)
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	_, err := c.GetTransactionHex("00")
+	var electrumErr *ElectrumError
+	if !errors.As(err, &electrumErr) || electrumErr.Code != 1 || electrumErr.Method != "blockchain.transaction.get" {
+		t.Errorf("expected *ElectrumError, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.PingCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	s.drop()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection drop not detected")
+	}
+	if _, err := c.RelayFee(); !errors.Is(err, ErrElectrumClosed) {
+		t.Errorf("expected ErrElectrumClosed, got %v", err)
+	}
+}
+
+func TestElectrumClient_WriteDeadline(t *testing.T) {
+	s := newElectrumServer(t)
+	defer s.close()
+	s.handle("server.ping", func(params []json.RawMessage) (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	})
+	c := dialTestElectrum(t, s)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.PingCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// The deadline of the first call has passed and must not affect this one.
+	if err := c.PingCtx(context.Background()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrBlindedSupply = errors.New("asset supply is blinded")
+
+// AssetID is a hex-encoded Elements asset id.
+type AssetID string
+
+// LiquidBTC is the native asset of the Liquid network.
+const LiquidBTC AssetID = "6f0279e9ed041c3d710a9f57d0c02928416460c4b722ae3457a11eec381c526d"
+
+// Issuance is an asset issuance or reissuance made by an input. The
+// amounts of a blinded issuance are only available as commitments.
+type Issuance struct {
+	AssetID               AssetID `json:"asset_id"`
+	IsReissuance          bool    `json:"is_reissuance"`
+	AssetBlindingNonce    string  `json:"asset_blinding_nonce,omitempty"`
+	AssetEntropy          string  `json:"asset_entropy,omitempty"`
+	ContractHash          string  `json:"contract_hash,omitempty"`
+	AssetAmount           Satoshi `json:"assetamount,omitempty"`
+	AssetAmountCommitment string  `json:"assetamountcommitment,omitempty"`
+	TokenAmount           Satoshi `json:"tokenamount,omitempty"`
+	TokenAmountCommitment string  `json:"tokenamountcommitment,omitempty"`
+}
+
+// Pegout is the Bitcoin destination of a peg-out output.
+type Pegout struct {
+	GenesisHash         BlockHash `json:"genesis_hash"`
+	ScriptPubKey        string    `json:"scriptpubkey"`
+	ScriptPubKeyAsm     string    `json:"scriptpubkey_asm"`
+	ScriptPubKeyAddress string    `json:"scriptpubkey_address,omitempty"`
+}
+
+// IsConfidential reports whether the value or asset of a Liquid output is
+// blinded, in which case Value or Asset is empty.
+func (o *TransactionOut) IsConfidential() bool {
+	return o.ValueCommitment != "" || o.AssetCommitment != ""
+}
+
+// AssetStats are the /asset/:id chain and mempool stats. Issued assets
+// report the issuance and reissuance token fields, the native asset the
+// peg-in, peg-out and burn fields. Amounts are in the asset's base units.
+type AssetStats struct {
+	TxCount                int32   `json:"tx_count"`
+	IssuanceCount          int32   `json:"issuance_count,omitempty"`
+	IssuedAmount           Satoshi `json:"issued_amount,omitempty"`
+	BurnedAmount           Satoshi `json:"burned_amount,omitempty"`
+	HasBlindedIssuances    bool    `json:"has_blinded_issuances,omitempty"`
+	ReissuanceTokens       Satoshi `json:"reissuance_tokens,omitempty"`
+	BurnedReissuanceTokens Satoshi `json:"burned_reissuance_tokens,omitempty"`
+	PegInCount             int32   `json:"peg_in_count,omitempty"`
+	PegInAmount            Satoshi `json:"peg_in_amount,omitempty"`
+	PegOutCount            int32   `json:"peg_out_count,omitempty"`
+	PegOutAmount           Satoshi `json:"peg_out_amount,omitempty"`
+	BurnCount              int32   `json:"burn_count,omitempty"`
+}
+
+type AssetTxIn struct {
+	ID  TxID  `json:"txid"`
+	VIn int32 `json:"vin"`
+}
+
+type AssetOutPoint struct {
+	ID   TxID  `json:"txid"`
+	VOut int32 `json:"vout"`
+}
+
+type AssetEntity struct {
+	Domain string `json:"domain"`
+}
+
+// Asset is an Elements asset. The issuance fields are absent for the native
+// asset, and Name, Ticker, Precision, Entity and Contract are only set for
+// assets listed in the asset registry.
+type Asset struct {
+	ID              AssetID            `json:"asset_id"`
+	IssuanceTxIn    *AssetTxIn         `json:"issuance_txin,omitempty"`
+	IssuancePrevOut *AssetOutPoint     `json:"issuance_prevout,omitempty"`
+	ReissuanceToken AssetID            `json:"reissuance_token,omitempty"`
+	ContractHash    string             `json:"contract_hash,omitempty"`
+	Status          *TransactionStatus `json:"status,omitempty"`
+	ChainStats      AssetStats         `json:"chain_stats"`
+	MemStats        AssetStats         `json:"mempool_stats"`
+	Name            string             `json:"name,omitempty"`
+	Ticker          string             `json:"ticker,omitempty"`
+	Precision       int32              `json:"precision,omitempty"`
+	Entity          *AssetEntity       `json:"entity,omitempty"`
+	Contract        json.RawMessage    `json:"contract,omitempty"`
+}
+
+func (a *Asset) IsNative() bool {
+	return a.IssuanceTxIn == nil
+}
+
+// Supply returns the circulating supply as electrs computes it: issued
+// minus burned for issued assets, pegged in minus pegged out and burned for
+// the native asset, mempool included. It fails with ErrBlindedSupply when an
+// issuance amount is blinded.
+func (a *Asset) Supply() (Satoshi, error) {
+	if a.IsNative() {
+		in, err := Sum(a.ChainStats.PegInAmount, a.MemStats.PegInAmount)
+		if err != nil {
+			return 0, err
+		}
+		out, err := Sum(a.ChainStats.PegOutAmount, a.MemStats.PegOutAmount, a.ChainStats.BurnedAmount, a.MemStats.BurnedAmount)
+		if err != nil {
+			return 0, err
+		}
+		return in.Sub(out)
+	}
+
+	if a.ChainStats.HasBlindedIssuances || a.MemStats.HasBlindedIssuances {
+		return 0, fmt.Errorf("%w: %s", ErrBlindedSupply, a.ID)
+	}
+	issued, err := Sum(a.ChainStats.IssuedAmount, a.MemStats.IssuedAmount)
+	if err != nil {
+		return 0, err
+	}
+	burned, err := Sum(a.ChainStats.BurnedAmount, a.MemStats.BurnedAmount)
+	if err != nil {
+		return 0, err
+	}
+	return issued.Sub(burned)
+}
+
+// FormatAmount renders base units as a decimal using the asset's
+// precision, which is 8 for the native asset, e.g. "1.50000000".
+func (a *Asset) FormatAmount(amount Satoshi) string {
+	precision := int(a.Precision)
+	if a.IsNative() {
+		precision = 8
+	}
+	if precision <= 0 {
+		return strconv.FormatInt(int64(amount), 10)
+	}
+
+	sign := ""
+	abs := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		abs = uint64(-amount)
+	}
+	unit := uint64(1)
+	for i := 0; i < precision; i++ {
+		unit *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, abs/unit, precision, abs%unit)
+}
+
+type AssetSortField string
+
+const (
+	AssetSortByName   AssetSortField = "name"
+	AssetSortByTicker AssetSortField = "ticker"
+	AssetSortByDomain AssetSortField = "domain"
+)
+
+// AssetRegistryQuery selects a page of the asset registry; zero fields use
+// the server defaults.
+type AssetRegistryQuery struct {
+	StartIndex int
+	Limit      int
+	SortField  AssetSortField
+	Descending bool
+}
+
+func (q AssetRegistryQuery) values() url.Values {
+	values := url.Values{}
+	if q.StartIndex > 0 {
+		values.Set("start_index", strconv.Itoa(q.StartIndex))
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.SortField != "" {
+		values.Set("sort_field", string(q.SortField))
+	}
+	if q.Descending {
+		values.Set("sort_dir", "desc")
+	}
+	return values
+}
+
+func (c *HTTPClient) GetAsset(assetID AssetID) (*Asset, error) {
+	return c.GetAssetCtx(context.Background(), assetID)
+}
+
+func (c *HTTPClient) GetAssetCtx(ctx context.Context, assetID AssetID) (*Asset, error) {
+	uri := fmt.Sprintf("/asset/%s", assetID)
+	result, err := c.doGet(ctx, uri, &Asset{})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Asset), nil
+}
+
+// GetAssetTransactions returns the issuance, reissuance, burn and peg
+// transactions of an asset, mempool first, like GetAddressTransactions.
+func (c *HTTPClient) GetAssetTransactions(assetID AssetID) ([]*Transaction, error) {
+	return c.GetAssetTransactionsCtx(context.Background(), assetID)
+}
+
+func (c *HTTPClient) GetAssetTransactionsCtx(ctx context.Context, assetID AssetID) ([]*Transaction, error) {
+	return c.getTransactions(ctx, fmt.Sprintf("/asset/%s/txs", assetID))
+}
+
+func (c *HTTPClient) GetAssetTransactionsLatest(assetID AssetID, lastTxID TxID) ([]*Transaction, error) {
+	return c.GetAssetTransactionsLatestCtx(context.Background(), assetID, lastTxID)
+}
+
+func (c *HTTPClient) GetAssetTransactionsLatestCtx(ctx context.Context, assetID AssetID, lastTxID TxID) ([]*Transaction, error) {
+	uri := fmt.Sprintf("/asset/%s/txs/chain", assetID)
+	if lastTxID != "" {
+		uri = fmt.Sprintf("%s/%s", uri, lastTxID)
+	}
+	return c.getTransactions(ctx, uri)
+}
+
+func (c *HTTPClient) GetAssetTransactionsInMemPool(assetID AssetID) ([]*Transaction, error) {
+	return c.GetAssetTransactionsInMemPoolCtx(context.Background(), assetID)
+}
+
+func (c *HTTPClient) GetAssetTransactionsInMemPoolCtx(ctx context.Context, assetID AssetID) ([]*Transaction, error) {
+	return c.getTransactions(ctx, fmt.Sprintf("/asset/%s/txs/mempool", assetID))
+}
+
+// GetAssetSupply returns the circulating supply in base units, or
+// ErrBlindedSupply when the server cannot compute it.
+func (c *HTTPClient) GetAssetSupply(assetID AssetID) (Satoshi, error) {
+	return c.GetAssetSupplyCtx(context.Background(), assetID)
+}
+
+func (c *HTTPClient) GetAssetSupplyCtx(ctx context.Context, assetID AssetID) (Satoshi, error) {
+	uri := fmt.Sprintf("/asset/%s/supply", assetID)
+	result, err := c.getSupply(ctx, uri)
+	if err != nil {
+		return 0, err
+	}
+	supply, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return 0, &DecodeError{Path: uri, Body: result, Err: err}
+	}
+	return Satoshi(supply), nil
+}
+
+// GetAssetSupplyDecimal returns the supply formatted with the asset's
+// precision, as Asset.FormatAmount does.
+func (c *HTTPClient) GetAssetSupplyDecimal(assetID AssetID) (string, error) {
+	return c.GetAssetSupplyDecimalCtx(context.Background(), assetID)
+}
+
+func (c *HTTPClient) GetAssetSupplyDecimalCtx(ctx context.Context, assetID AssetID) (string, error) {
+	return c.getSupply(ctx, fmt.Sprintf("/asset/%s/supply/decimal", assetID))
+}
+
+// GetAssetRegistry returns a page of the assets listed in the registry and
+// the total number of listed assets.
+func (c *HTTPClient) GetAssetRegistry(query AssetRegistryQuery) ([]*Asset, int, error) {
+	return c.GetAssetRegistryCtx(context.Background(), query)
+}
+
+func (c *HTTPClient) GetAssetRegistryCtx(ctx context.Context, query AssetRegistryQuery) ([]*Asset, int, error) {
+	uri := "/assets/registry"
+	resp, err := c.doGetResponse(ctx, uri, query.values())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	assets := make([]*Asset, 0)
+	if err := decode(uri, resp.Body(), &assets); err != nil {
+		return nil, 0, err
+	}
+	total := len(assets)
+	if header := resp.Header().Get("X-Total-Results"); header != "" {
+		if total, err = strconv.Atoi(header); err != nil {
+			return nil, 0, &DecodeError{Path: uri, Body: header, Err: err}
+		}
+	}
+	return assets, total, nil
+}
+
+func (c *HTTPClient) getTransactions(ctx context.Context, uri string) ([]*Transaction, error) {
+	result, err := c.doGetBody(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*Transaction, 0)
+	if err := decode(uri, result, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (c *HTTPClient) getSupply(ctx context.Context, uri string) (string, error) {
+	result, err := c.doGetBody(ctx, uri)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Body, "blinded") {
+			return "", fmt.Errorf("%w: %s", ErrBlindedSupply, uri)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(result)), nil
+}
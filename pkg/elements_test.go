@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const usdtAssetID = AssetID("ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2")
+
+func TestHTTPClient_GetAsset(t *testing.T) {
+	asset, err := fixtureClient.GetAsset(usdtAssetID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if asset.IsNative() || asset.Ticker != "USDt" || asset.Precision != 8 || asset.Entity == nil || asset.Entity.Domain != "tether.to" {
+		t.Errorf("unexpected asset %+v", asset)
+	}
+	if asset.IssuanceTxIn.ID != "2329c2243e45874b73996fed6729ebc57d71caa52be278dc73a2e49842f420da" || asset.Status == nil || !asset.Status.Confirmed {
+		t.Errorf("unexpected issuance %+v %+v", asset.IssuanceTxIn, asset.Status)
+	}
+	if supply, err := asset.Supply(); err != nil || supply != 99997000000000 {
+		t.Errorf("unexpected supply %d %v", supply, err)
+	}
+	if s := asset.FormatAmount(99997000000000); s != "999970.00000000" {
+		t.Errorf("unexpected amount %s", s)
+	}
+
+	native, err := fixtureClient.GetAsset(LiquidBTC)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !native.IsNative() || native.ChainStats.PegInCount != 4120 || native.MemStats.PegOutAmount != 20000000 {
+		t.Errorf("unexpected native asset %+v", native)
+	}
+	if supply, err := native.Supply(); err != nil || supply != 202374678901 {
+		t.Errorf("unexpected supply %d %v", supply, err)
+	}
+	if s := native.FormatAmount(-150000000); s != "-1.50000000" {
+		t.Errorf("unexpected amount %s", s)
+	}
+}
+
+func TestHTTPClient_GetAssetTransactions(t *testing.T) {
+	transactions, err := fixtureClient.GetAssetTransactions(usdtAssetID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("unexpected transactions %v", transactions)
+	}
+
+	peg := transactions[0]
+	if !peg.VIn[0].IsPegin || peg.VIn[0].PrevOut != (TransactionOut{}) {
+		t.Errorf("unexpected peg-in %+v", peg.VIn[0])
+	}
+	pegout := peg.VOut[0].Pegout
+	if pegout == nil || pegout.GenesisHash != "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f" || peg.VOut[0].Asset != LiquidBTC {
+		t.Errorf("unexpected peg-out %+v", peg.VOut[0])
+	}
+
+	issuance := transactions[1].VIn[0].Issuance
+	if issuance == nil || issuance.AssetID != usdtAssetID || issuance.IsReissuance || issuance.AssetAmount != 100000000000000 || issuance.TokenAmount != 100000000 {
+		t.Errorf("unexpected issuance %+v", issuance)
+	}
+	outs := transactions[1].VOut
+	if outs[0].IsConfidential() || outs[0].Asset != usdtAssetID {
+		t.Errorf("unexpected explicit output %+v", outs[0])
+	}
+	if !outs[1].IsConfidential() || outs[1].Value != 0 || outs[1].Asset != "" {
+		t.Errorf("unexpected confidential output %+v", outs[1])
+	}
+	if outs[2].ScriptPubKeyType != "fee" || outs[2].Value != transactions[1].Fee {
+		t.Errorf("unexpected fee output %+v", outs[2])
+	}
+}
+
+func TestHTTPClient_GetAssetSupply(t *testing.T) {
+	supply, err := fixtureClient.GetAssetSupply(usdtAssetID)
+	if err != nil || supply != 99997000000000 {
+		t.Errorf("unexpected supply %d %v", supply, err)
+	}
+	decimal, err := fixtureClient.GetAssetSupplyDecimal(usdtAssetID)
+	if err != nil || decimal != "999970.00000000" {
+		t.Errorf("unexpected supply %s %v", decimal, err)
+	}
+
+	if _, err := fixtureClient.GetAssetSupply(blindedAssetID); !errors.Is(err, ErrBlindedSupply) {
+		t.Errorf("expected ErrBlindedSupply, got %v", err)
+	}
+	blinded := &Asset{ID: blindedAssetID, IssuanceTxIn: &AssetTxIn{}, MemStats: AssetStats{HasBlindedIssuances: true}}
+	if _, err := blinded.Supply(); !errors.Is(err, ErrBlindedSupply) {
+		t.Errorf("expected ErrBlindedSupply, got %v", err)
+	}
+}
+
+func TestHTTPClient_GetAssetRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/assets/registry" || r.URL.RawQuery != "limit=1&sort_dir=desc&sort_field=name&start_index=10" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, err := ioutil.ReadFile(filepath.Join("testdata", "assets_registry.json"))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Total-Results", "131")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	query := AssetRegistryQuery{StartIndex: 10, Limit: 1, SortField: AssetSortByName, Descending: true}
+	assets, total, err := NewHTTPClient(server.URL, false).GetAssetRegistry(query)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if total != 131 || len(assets) != 1 || assets[0].Name != "Tether USD" {
+		t.Errorf("unexpected registry page %d %v", total, assets)
+	}
+}
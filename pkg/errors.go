@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by *APIError through errors.Is.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrBadRequest  = errors.New("bad request")
+	ErrRateLimited = errors.New("rate limited")
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// APIError is returned when electrs answers with a non-2xx status code.
+type APIError struct {
+	StatusCode int
+	Path       string
+	Body       string
+	Header     http.Header
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request err: %s returned %d: %s", e.Path, e.StatusCode, e.Body)
+}
+
+// Is maps the status code onto the package sentinels, so that
+// errors.Is(err, ErrNotFound) holds for a 404 and so on. Every 5xx is
+// reported as ErrUnavailable.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// TransportError is returned when the request never produced an HTTP
+// response, e.g. connection refused or reset.
+type TransportError struct {
+	Path string
+	Err  error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("conn err: %s: %s", e.Path, e.Err.Error())
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when a successful response body cannot be parsed.
+type DecodeError struct {
+	Path string
+	Body string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode err: %s: %s", e.Path, e.Err.Error())
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Sentinel errors matched by *BroadcastError through errors.Is. They follow
+// the reject reasons bitcoind reports from sendrawtransaction.
+var (
+	ErrTxDecodeFailed           = errors.New("tx decode failed")
+	ErrMinRelayFeeNotMet        = errors.New("min relay fee not met")
+	ErrMempoolMinFeeNotMet      = errors.New("mempool min fee not met")
+	ErrInsufficientFee          = errors.New("insufficient fee")
+	ErrAbsurdFee                = errors.New("absurdly high fee")
+	ErrMempoolConflict          = errors.New("txn-mempool-conflict")
+	ErrMissingInputs            = errors.New("missing or spent inputs")
+	ErrAlreadyInMempool         = errors.New("txn-already-in-mempool")
+	ErrAlreadyInChain           = errors.New("transaction already in block chain")
+	ErrNonFinal                 = errors.New("non-final")
+	ErrDust                     = errors.New("dust")
+	ErrMempoolFull              = errors.New("mempool full")
+	ErrScriptVerificationFailed = errors.New("script verification failed")
+)
+
+var rejectReasons = []struct {
+	match    string
+	sentinel error
+}{
+	{"tx decode failed", ErrTxDecodeFailed},
+	{"min relay fee not met", ErrMinRelayFeeNotMet},
+	{"mempool min fee not met", ErrMempoolMinFeeNotMet},
+	{"insufficient fee", ErrInsufficientFee},
+	{"absurdly-high-fee", ErrAbsurdFee},
+	{"max-fee-exceeded", ErrAbsurdFee},
+	{"txn-mempool-conflict", ErrMempoolConflict},
+	{"bad-txns-inputs-missingorspent", ErrMissingInputs},
+	{"missing-inputs", ErrMissingInputs},
+	{"missing inputs", ErrMissingInputs},
+	{"txn-already-in-mempool", ErrAlreadyInMempool},
+	{"txn-already-known", ErrAlreadyInMempool},
+	{"already in block chain", ErrAlreadyInChain},
+	{"txn-already-confirmed", ErrAlreadyInChain},
+	{"non-final", ErrNonFinal},
+	{"non-bip68-final", ErrNonFinal},
+	{"dust", ErrDust},
+	{"mempool full", ErrMempoolFull},
+	{"script-verify-flag", ErrScriptVerificationFailed},
+}
+
+// BroadcastError is returned by BroadcastTransaction when the node rejects
+// the transaction. Code and Reason come from the bitcoind RPC error embedded
+// in the response body; Reason is the raw body when none could be found.
+// API is nil when the broadcast went through the Electrum protocol.
+type BroadcastError struct {
+	Code   int
+	Reason string
+	API    *APIError
+}
+
+func (e *BroadcastError) Error() string {
+	return fmt.Sprintf("broadcast rejected: %s", e.Reason)
+}
+
+func (e *BroadcastError) Is(target error) bool {
+	return e.sentinel() == target
+}
+
+func (e *BroadcastError) Unwrap() error {
+	if e.API == nil {
+		return nil
+	}
+	return e.API
+}
+
+func (e *BroadcastError) sentinel() error {
+	reason := strings.ToLower(e.Reason)
+	for _, r := range rejectReasons {
+		if strings.Contains(reason, r.match) {
+			return r.sentinel
+		}
+	}
+	return nil
+}
+
+// newBroadcastError extracts the RPC error from bodies such as
+//
+//	sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 0 < 141"}
+func newBroadcastError(apiErr *APIError) *BroadcastError {
+	broadcastErr := parseRejectMessage(apiErr.Body)
+	broadcastErr.API = apiErr
+	return broadcastErr
+}
+
+func parseRejectMessage(message string) *BroadcastError {
+	broadcastErr := &BroadcastError{Reason: strings.TrimSpace(message)}
+	if i := strings.Index(message, "{"); i >= 0 {
+		rpcErr := struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}{}
+		if err := json.Unmarshal([]byte(message[i:]), &rpcErr); err == nil && rpcErr.Message != "" {
+			broadcastErr.Code = rpcErr.Code
+			broadcastErr.Reason = rpcErr.Message
+		}
+	}
+	return broadcastErr
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError_Is(t *testing.T) {
+	cases := []struct {
+		status   int
+		body     string
+		sentinel error
+	}{
+		{http.StatusNotFound, "Transaction not found", ErrNotFound},
+		{http.StatusBadRequest, "Invalid hex string", ErrBadRequest},
+		{http.StatusTooManyRequests, "Too Many Requests", ErrRateLimited},
+		{http.StatusServiceUnavailable, "Service Unavailable", ErrUnavailable},
+		{http.StatusBadGateway, "Bad Gateway", ErrUnavailable},
+	}
+
+	for _, tc := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			_, _ = w.Write([]byte(tc.body))
+		}))
+
+		_, err := NewHTTPClient(server.URL, false).GetTransaction("00")
+		server.Close()
+
+		if !errors.Is(err, tc.sentinel) {
+			t.Errorf("status %d: expected %v, got %v", tc.status, tc.sentinel, err)
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: expected *APIError, got %T", tc.status, err)
+		}
+		if apiErr.StatusCode != tc.status || apiErr.Body != tc.body || apiErr.Path != "/tx/00" {
+			t.Errorf("status %d: unexpected api error %+v", tc.status, apiErr)
+		}
+	}
+}
+
+func TestAPIError_NonOKSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"confirmed":true,"block_height":100}`))
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPClient(server.URL, false).GetTransactionStatus("00")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !status.Confirmed || status.BlockHeight != 100 {
+		t.Errorf("invalid transaction status")
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, err := NewHTTPClient(url, false).GetLastBlockHash()
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("expected *TransportError, got %T: %v", err, err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("transport error must not match api sentinels")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`not a height`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClient(server.URL, false).GetLastBlockHeight()
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+	}
+}
+
+func TestBroadcastError_Is(t *testing.T) {
+	cases := []struct {
+		body     string
+		sentinel error
+	}{
+		{`sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 0 < 141"}`, ErrMinRelayFeeNotMet},
+		{`sendrawtransaction RPC error: {"code":-26,"message":"mempool min fee not met, 1000 < 1200"}`, ErrMempoolMinFeeNotMet},
+		{`sendrawtransaction RPC error: {"code":-26,"message":"txn-mempool-conflict"}`, ErrMempoolConflict},
+		{`sendrawtransaction RPC error: {"code":-25,"message":"bad-txns-inputs-missingorspent"}`, ErrMissingInputs},
+		{`sendrawtransaction RPC error: {"code":-27,"message":"Transaction already in block chain"}`, ErrAlreadyInChain},
+		{`sendrawtransaction RPC error: {"code":-22,"message":"TX decode failed"}`, ErrTxDecodeFailed},
+		{`sendrawtransaction RPC error: {"code":-26,"message":"non-mandatory-script-verify-flag (Signature must be zero for failed CHECK(MULTI)SIG operation)"}`, ErrScriptVerificationFailed},
+		{`sendrawtransaction RPC error: {"code":-26,"message":"non-BIP68-final"}`, ErrNonFinal},
+		{`insufficient fee, rejecting replacement`, ErrInsufficientFee},
+	}
+
+	for _, tc := range cases {
+		err := newBroadcastError(&APIError{StatusCode: http.StatusBadRequest, Path: "/tx", Body: tc.body})
+		if !errors.Is(err, tc.sentinel) {
+			t.Errorf("%q: expected %v", tc.body, tc.sentinel)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"context"
+	"io"
+)
+
+// Esplora is the full REST API implemented by HTTPClient. Depend on it, or on
+// one of the narrower interfaces below, to be able to swap in a fake such as
+// esploratest.Fake.
+type Esplora interface {
+	TxAPI
+	AddressAPI
+	BlockAPI
+	MempoolAPI
+}
+
+var _ Esplora = (*HTTPClient)(nil)
+
+// Elements is the REST API of an electrs serving Liquid or another
+// Elements chain. HTTPClient implements it; against a Bitcoin server the
+// AssetAPI calls fail with ErrNotFound.
+type Elements interface {
+	Esplora
+	AssetAPI
+}
+
+var _ Elements = (*HTTPClient)(nil)
+
+// TxAPI covers the /tx endpoints.
+type TxAPI interface {
+	GetTransaction(txID TxID) (*Transaction, error)
+	GetTransactionCtx(ctx context.Context, txID TxID) (*Transaction, error)
+	GetTransactionStatus(txID TxID) (*TransactionStatus, error)
+	GetTransactionStatusCtx(ctx context.Context, txID TxID) (*TransactionStatus, error)
+	GetTransactionHex(txID TxID) (TxHex, error)
+	GetTransactionHexCtx(ctx context.Context, txID TxID) (TxHex, error)
+	GetTransactionMerkleProof(txID TxID) (*TransactionMerkleProof, error)
+	GetTransactionMerkleProofCtx(ctx context.Context, txID TxID) (*TransactionMerkleProof, error)
+	GetTransactionOutSpend(txID TxID, vOut int32) (*TransactionOutSpend, error)
+	GetTransactionOutSpendCtx(ctx context.Context, txID TxID, vOut int32) (*TransactionOutSpend, error)
+	GetTransactionOutSpends(txID TxID) ([]*TransactionOutSpend, error)
+	GetTransactionOutSpendsCtx(ctx context.Context, txID TxID) ([]*TransactionOutSpend, error)
+	BroadcastTransaction(txHex TxHex) (TxID, error)
+	BroadcastTransactionCtx(ctx context.Context, txHex TxHex) (TxID, error)
+}
+
+// AddressAPI covers the /address, /address-prefix and /scripthash endpoints.
+type AddressAPI interface {
+	GetAddressInfo(address Address) (*AddressInfo, error)
+	GetAddressInfoCtx(ctx context.Context, address Address) (*AddressInfo, error)
+	GetScriptHashInfo(hash ScriptHash) (*ScriptHashInfo, error)
+	GetScriptHashInfoCtx(ctx context.Context, hash ScriptHash) (*ScriptHashInfo, error)
+	SearchAddressPrefix(prefix string) ([]Address, error)
+	SearchAddressPrefixCtx(ctx context.Context, prefix string) ([]Address, error)
+	GetAddressTransactions(address Address) ([]*Transaction, error)
+	GetAddressTransactionsCtx(ctx context.Context, address Address) ([]*Transaction, error)
+	GetScriptHashTransactions(hash ScriptHash) ([]*Transaction, error)
+	GetScriptHashTransactionsCtx(ctx context.Context, hash ScriptHash) ([]*Transaction, error)
+	GetAddressTransactionsLatest(address Address, lastTxID TxID) ([]*Transaction, error)
+	GetAddressTransactionsLatestCtx(ctx context.Context, address Address, lastTxID TxID) ([]*Transaction, error)
+	GetScriptHashTransactionsLatest(address Address, lastTxID TxID) ([]*Transaction, error)
+	GetScriptHashTransactionsLatestCtx(ctx context.Context, address Address, lastTxID TxID) ([]*Transaction, error)
+	GetAddressTransactionsInMemPool(address Address) ([]*Transaction, error)
+	GetAddressTransactionsInMemPoolCtx(ctx context.Context, address Address) ([]*Transaction, error)
+	GetScriptHashTransactionsInMemPool(hash ScriptHash) ([]*Transaction, error)
+	GetScriptHashTransactionsInMemPoolCtx(ctx context.Context, hash ScriptHash) ([]*Transaction, error)
+	GetAddressUnspentTxOutputs(address Address) ([]*UnspentTransactionOutput, error)
+	GetAddressUnspentTxOutputsCtx(ctx context.Context, address Address) ([]*UnspentTransactionOutput, error)
+	GetScriptHashUnspentTxOutputs(hash ScriptHash) ([]*UnspentTransactionOutput, error)
+	GetScriptHashUnspentTxOutputsCtx(ctx context.Context, hash ScriptHash) ([]*UnspentTransactionOutput, error)
+}
+
+// BlockAPI covers the /block, /block-height and /blocks endpoints.
+type BlockAPI interface {
+	GetBlock(hash BlockHash) (*Block, error)
+	GetBlockCtx(ctx context.Context, hash BlockHash) (*Block, error)
+	GetBlockStatus(hash BlockHash) (*BlockStatus, error)
+	GetBlockStatusCtx(ctx context.Context, hash BlockHash) (*BlockStatus, error)
+	GetBlockHeader(hash BlockHash) (BlockHeaderHex, error)
+	GetBlockHeaderCtx(ctx context.Context, hash BlockHash) (BlockHeaderHex, error)
+	GetRawBlock(hash BlockHash) (io.ReadCloser, error)
+	GetRawBlockCtx(ctx context.Context, hash BlockHash) (io.ReadCloser, error)
+	GetBlockTransactions(hash BlockHash, startIndex int32) ([]*Transaction, error)
+	GetBlockTransactionsCtx(ctx context.Context, hash BlockHash, startIndex int32) ([]*Transaction, error)
+	GetBlockTxIDs(hash BlockHash) ([]TxID, error)
+	GetBlockTxIDsCtx(ctx context.Context, hash BlockHash) ([]TxID, error)
+	GetBlockTxID(hash BlockHash, index int32) (TxID, error)
+	GetBlockTxIDCtx(ctx context.Context, hash BlockHash, index int32) (TxID, error)
+	GetBlockHash(height BlockHeight) (BlockHash, error)
+	GetBlockHashCtx(ctx context.Context, height BlockHeight) (BlockHash, error)
+	GetBlocks(height BlockHeight) (Blocks, error)
+	GetBlocksCtx(ctx context.Context, height BlockHeight) (Blocks, error)
+	GetLastBlockHeight() (BlockHeight, error)
+	GetLastBlockHeightCtx(ctx context.Context) (BlockHeight, error)
+	GetLastBlockHash() (BlockHash, error)
+	GetLastBlockHashCtx(ctx context.Context) (BlockHash, error)
+}
+
+// MempoolAPI covers the /mempool and /fee-estimates endpoints.
+type MempoolAPI interface {
+	GetMemPoolStatistics() (*MemPoolStatistics, error)
+	GetMemPoolStatisticsCtx(ctx context.Context) (*MemPoolStatistics, error)
+	GetMemPoolTxIDs() ([]TxID, error)
+	GetMemPoolTxIDsCtx(ctx context.Context) ([]TxID, error)
+	GetMemPoolRecentOverviews() ([]*MemPoolOverviewData, error)
+	GetMemPoolRecentOverviewsCtx(ctx context.Context) ([]*MemPoolOverviewData, error)
+	GetFeeEstimates() (*FeeEstimates, error)
+	GetFeeEstimatesCtx(ctx context.Context) (*FeeEstimates, error)
+}
+
+// AssetAPI covers the Elements-only /asset and /assets endpoints.
+type AssetAPI interface {
+	GetAsset(assetID AssetID) (*Asset, error)
+	GetAssetCtx(ctx context.Context, assetID AssetID) (*Asset, error)
+	GetAssetTransactions(assetID AssetID) ([]*Transaction, error)
+	GetAssetTransactionsCtx(ctx context.Context, assetID AssetID) ([]*Transaction, error)
+	GetAssetTransactionsLatest(assetID AssetID, lastTxID TxID) ([]*Transaction, error)
+	GetAssetTransactionsLatestCtx(ctx context.Context, assetID AssetID, lastTxID TxID) ([]*Transaction, error)
+	GetAssetTransactionsInMemPool(assetID AssetID) ([]*Transaction, error)
+	GetAssetTransactionsInMemPoolCtx(ctx context.Context, assetID AssetID) ([]*Transaction, error)
+	GetAssetSupply(assetID AssetID) (Satoshi, error)
+	GetAssetSupplyCtx(ctx context.Context, assetID AssetID) (Satoshi, error)
+	GetAssetSupplyDecimal(assetID AssetID) (string, error)
+	GetAssetSupplyDecimalCtx(ctx context.Context, assetID AssetID) (string, error)
+	GetAssetRegistry(query AssetRegistryQuery) ([]*Asset, int, error)
+	GetAssetRegistryCtx(ctx context.Context, query AssetRegistryQuery) ([]*Asset, int, error)
+}
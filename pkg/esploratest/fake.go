@@ -0,0 +1,1255 @@
+// Package esploratest provides an in-memory implementation of pkg.Esplora
+// for tests that should not depend on a live electrs.
+package esploratest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	blockTxsPageSize     = 25
+	chainTxsPageSize     = 25
+	mempoolTxsPageSize   = 50
+	blocksPageSize       = 10
+	recentMemPoolTxCount = 10
+	histogramBinVSize    = 100000
+	registryPageSize     = 25
+	addressPrefixLimit   = 10
+)
+
+// Fake is a seedable, concurrency-safe stand-in for pkg.HTTPClient. It
+// stores and returns deep copies, so callers may keep or modify what they
+// pass in and get back. Address and scripthash stats, history, UTXOs and
+// outspends are derived from the seeded transactions, so a test only has to
+// describe the chain itself.
+// Missing entities are reported with the same *pkg.APIError values a real
+// server would produce, so errors.Is(err, pkg.ErrNotFound) works unchanged.
+type Fake struct {
+	// OnBroadcast, when set, decides the outcome of BroadcastTransaction.
+	// By default the txid is the double-SHA256 of the raw bytes, which is
+	// only correct for transactions without witness data.
+	OnBroadcast func(txHex pkg.TxHex) (pkg.TxID, error)
+
+	mu          sync.Mutex
+	txs         map[pkg.TxID]*pkg.Transaction
+	seq         map[pkg.TxID]int
+	nextSeq     int
+	hexes       map[pkg.TxID]pkg.TxHex
+	blocks      map[pkg.BlockHash]*pkg.Block
+	blockTxIDs  map[pkg.BlockHash][]pkg.TxID
+	heights     map[pkg.BlockHeight]pkg.BlockHash
+	mempool     []pkg.TxID
+	fees        pkg.FeeEstimates
+	assets      map[pkg.AssetID]*pkg.Asset
+	err         error
+	broadcasted []pkg.TxHex
+}
+
+var _ pkg.Elements = (*Fake)(nil)
+
+func New() *Fake {
+	return &Fake{
+		txs:        make(map[pkg.TxID]*pkg.Transaction),
+		seq:        make(map[pkg.TxID]int),
+		hexes:      make(map[pkg.TxID]pkg.TxHex),
+		blocks:     make(map[pkg.BlockHash]*pkg.Block),
+		blockTxIDs: make(map[pkg.BlockHash][]pkg.TxID),
+		heights:    make(map[pkg.BlockHeight]pkg.BlockHash),
+		fees:       make(pkg.FeeEstimates),
+		assets:     make(map[pkg.AssetID]*pkg.Asset),
+	}
+}
+
+// AddTransaction stores tx. Unconfirmed transactions enter the mempool.
+func (f *Fake) AddTransaction(tx *pkg.Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addTransaction(copyTransaction(tx))
+}
+
+// AddBlock stores block as the best block at its height, replacing any
+// previous one, and confirms txs in it in the given order.
+func (f *Fake) AddBlock(block *pkg.Block, txs ...*pkg.Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if previous, ok := f.heights[block.Height]; ok && previous != block.ID {
+		f.disconnect(previous)
+	}
+
+	block = copyBlock(block)
+	txIDs := make([]pkg.TxID, 0, len(txs))
+	for _, tx := range txs {
+		tx = copyTransaction(tx)
+		tx.Status = pkg.TransactionStatus{
+			Confirmed:   true,
+			BlockHeight: block.Height,
+			BlockHash:   string(block.ID),
+			BlockTime:   block.Timestamp,
+		}
+		f.addTransaction(tx)
+		txIDs = append(txIDs, tx.ID)
+	}
+	if block.TxCount == 0 {
+		block.TxCount = int32(len(txs))
+	}
+
+	f.blocks[block.ID] = block
+	f.blockTxIDs[block.ID] = txIDs
+	f.heights[block.Height] = block.ID
+}
+
+// RemoveTransaction forgets tx, e.g. to simulate a mempool eviction.
+func (f *Fake) RemoveTransaction(txID pkg.TxID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.txs, txID)
+	delete(f.seq, txID)
+	delete(f.hexes, txID)
+	f.removeFromMempool(txID)
+}
+
+func (f *Fake) SetTransactionHex(txID pkg.TxID, txHex pkg.TxHex) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hexes[txID] = txHex
+}
+
+func (f *Fake) SetFeeEstimates(fees pkg.FeeEstimates) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fees = copyFeeEstimates(fees)
+}
+
+// AddAsset stores asset as returned by GetAsset. Its stats are taken as
+// given; asset transactions are the seeded ones with an output of the
+// asset. Assets with a Name are listed in the registry.
+func (f *Fake) AddAsset(asset *pkg.Asset) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assets[asset.ID] = copyAsset(asset)
+}
+
+// SetError makes every call fail with err until it is cleared with nil.
+func (f *Fake) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Broadcasted returns every transaction accepted by BroadcastTransaction.
+func (f *Fake) Broadcasted() []pkg.TxHex {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]pkg.TxHex(nil), f.broadcasted...)
+}
+
+func (f *Fake) GetTransaction(txID pkg.TxID) (*pkg.Transaction, error) {
+	return f.GetTransactionCtx(context.Background(), txID)
+}
+
+func (f *Fake) GetTransactionCtx(ctx context.Context, txID pkg.TxID) (*pkg.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, ok := f.txs[txID]
+	if !ok {
+		return nil, txNotFound(fmt.Sprintf("/tx/%s", txID))
+	}
+	return copyTransaction(tx), nil
+}
+
+func (f *Fake) GetTransactionStatus(txID pkg.TxID) (*pkg.TransactionStatus, error) {
+	return f.GetTransactionStatusCtx(context.Background(), txID)
+}
+
+func (f *Fake) GetTransactionStatusCtx(ctx context.Context, txID pkg.TxID) (*pkg.TransactionStatus, error) {
+	tx, err := f.GetTransactionCtx(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	status := tx.Status
+	return &status, nil
+}
+
+func (f *Fake) GetTransactionHex(txID pkg.TxID) (pkg.TxHex, error) {
+	return f.GetTransactionHexCtx(context.Background(), txID)
+}
+
+func (f *Fake) GetTransactionHexCtx(ctx context.Context, txID pkg.TxID) (pkg.TxHex, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return "", err
+	}
+
+	txHex, ok := f.hexes[txID]
+	if !ok {
+		return "", txNotFound(fmt.Sprintf("/tx/%s/hex", txID))
+	}
+	return txHex, nil
+}
+
+func (f *Fake) GetTransactionMerkleProof(txID pkg.TxID) (*pkg.TransactionMerkleProof, error) {
+	return f.GetTransactionMerkleProofCtx(context.Background(), txID)
+}
+
+func (f *Fake) GetTransactionMerkleProofCtx(ctx context.Context, txID pkg.TxID) (*pkg.TransactionMerkleProof, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("/tx/%s/merkle-proof", txID)
+	tx, ok := f.txs[txID]
+	if !ok || !tx.Status.Confirmed {
+		return nil, txNotFound(uri)
+	}
+
+	txIDs := f.blockTxIDs[pkg.BlockHash(tx.Status.BlockHash)]
+	for pos, id := range txIDs {
+		if id == txID {
+			merkle, err := merkleBranch(txIDs, pos)
+			if err != nil {
+				return nil, &pkg.APIError{StatusCode: http.StatusInternalServerError, Path: uri, Body: err.Error()}
+			}
+			return &pkg.TransactionMerkleProof{BlockHeight: tx.Status.BlockHeight, Merkle: merkle, Pos: int32(pos)}, nil
+		}
+	}
+	return nil, txNotFound(uri)
+}
+
+func (f *Fake) GetTransactionOutSpend(txID pkg.TxID, vOut int32) (*pkg.TransactionOutSpend, error) {
+	return f.GetTransactionOutSpendCtx(context.Background(), txID, vOut)
+}
+
+func (f *Fake) GetTransactionOutSpendCtx(ctx context.Context, txID pkg.TxID, vOut int32) (*pkg.TransactionOutSpend, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, ok := f.txs[txID]
+	if !ok || vOut < 0 || int(vOut) >= len(tx.VOut) {
+		return nil, txNotFound(fmt.Sprintf("/tx/%s/outspend/%d", txID, vOut))
+	}
+	return f.outSpend(txID, vOut), nil
+}
+
+func (f *Fake) GetTransactionOutSpends(txID pkg.TxID) ([]*pkg.TransactionOutSpend, error) {
+	return f.GetTransactionOutSpendsCtx(context.Background(), txID)
+}
+
+func (f *Fake) GetTransactionOutSpendsCtx(ctx context.Context, txID pkg.TxID) ([]*pkg.TransactionOutSpend, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, ok := f.txs[txID]
+	if !ok {
+		return nil, txNotFound(fmt.Sprintf("/tx/%s/outspends", txID))
+	}
+	outSpends := make([]*pkg.TransactionOutSpend, 0, len(tx.VOut))
+	for vOut := range tx.VOut {
+		outSpends = append(outSpends, f.outSpend(txID, int32(vOut)))
+	}
+	return outSpends, nil
+}
+
+func (f *Fake) BroadcastTransaction(txHex pkg.TxHex) (pkg.TxID, error) {
+	return f.BroadcastTransactionCtx(context.Background(), txHex)
+}
+
+func (f *Fake) BroadcastTransactionCtx(ctx context.Context, txHex pkg.TxHex) (pkg.TxID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return "", err
+	}
+
+	var txID pkg.TxID
+	if f.OnBroadcast != nil {
+		id, err := f.OnBroadcast(txHex)
+		if err != nil {
+			return "", err
+		}
+		txID = id
+	} else {
+		raw, err := hex.DecodeString(string(txHex))
+		if err != nil || len(raw) == 0 {
+			body := `sendrawtransaction RPC error: {"code":-22,"message":"TX decode failed"}`
+			return "", &pkg.BroadcastError{
+				Code:   -22,
+				Reason: "TX decode failed",
+				API:    &pkg.APIError{StatusCode: http.StatusBadRequest, Path: "/tx", Body: body},
+			}
+		}
+		txID = pkg.TxID(hex.EncodeToString(reversed(doubleSHA256(raw))))
+	}
+
+	f.broadcasted = append(f.broadcasted, txHex)
+	f.hexes[txID] = txHex
+	return txID, nil
+}
+
+func (f *Fake) GetAddressInfo(address pkg.Address) (*pkg.AddressInfo, error) {
+	return f.GetAddressInfoCtx(context.Background(), address)
+}
+
+func (f *Fake) GetAddressInfoCtx(ctx context.Context, address pkg.Address) (*pkg.AddressInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	chainStats, memStats := f.stats(matchAddress(address))
+	return &pkg.AddressInfo{Address: address, ChainStats: chainStats, MemStats: pkg.MemStats(memStats)}, nil
+}
+
+func (f *Fake) GetScriptHashInfo(hash pkg.ScriptHash) (*pkg.ScriptHashInfo, error) {
+	return f.GetScriptHashInfoCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetScriptHashInfoCtx(ctx context.Context, hash pkg.ScriptHash) (*pkg.ScriptHashInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	chainStats, memStats := f.stats(matchScriptHash(hash))
+	return &pkg.ScriptHashInfo{ScriptHash: hash, ChainStats: chainStats, MemStats: pkg.MemStats(memStats)}, nil
+}
+
+// SearchAddressPrefix matches the output addresses of the seeded
+// transactions, sorted and capped at 10 like electrs.
+func (f *Fake) SearchAddressPrefix(prefix string) ([]pkg.Address, error) {
+	return f.SearchAddressPrefixCtx(context.Background(), prefix)
+}
+
+func (f *Fake) SearchAddressPrefixCtx(ctx context.Context, prefix string) ([]pkg.Address, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[pkg.Address]bool)
+	addresses := make([]pkg.Address, 0)
+	for _, tx := range f.txs {
+		for _, out := range tx.VOut {
+			address := pkg.Address(out.ScriptPubKeyAddress)
+			if address != "" && strings.HasPrefix(string(address), prefix) && !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	if len(addresses) > addressPrefixLimit {
+		addresses = addresses[:addressPrefixLimit]
+	}
+	return addresses, nil
+}
+
+func (f *Fake) GetAddressTransactions(address pkg.Address) ([]*pkg.Transaction, error) {
+	return f.GetAddressTransactionsCtx(context.Background(), address)
+}
+
+func (f *Fake) GetAddressTransactionsCtx(ctx context.Context, address pkg.Address) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAddress(address), true, true, "")
+}
+
+func (f *Fake) GetScriptHashTransactions(hash pkg.ScriptHash) ([]*pkg.Transaction, error) {
+	return f.GetScriptHashTransactionsCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetScriptHashTransactionsCtx(ctx context.Context, hash pkg.ScriptHash) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchScriptHash(hash), true, true, "")
+}
+
+func (f *Fake) GetAddressTransactionsLatest(address pkg.Address, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.GetAddressTransactionsLatestCtx(context.Background(), address, lastTxID)
+}
+
+func (f *Fake) GetAddressTransactionsLatestCtx(ctx context.Context, address pkg.Address, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAddress(address), false, true, lastTxID)
+}
+
+func (f *Fake) GetScriptHashTransactionsLatest(address pkg.Address, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.GetScriptHashTransactionsLatestCtx(context.Background(), address, lastTxID)
+}
+
+func (f *Fake) GetScriptHashTransactionsLatestCtx(ctx context.Context, address pkg.Address, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchScriptHash(pkg.ScriptHash(address)), false, true, lastTxID)
+}
+
+func (f *Fake) GetAddressTransactionsInMemPool(address pkg.Address) ([]*pkg.Transaction, error) {
+	return f.GetAddressTransactionsInMemPoolCtx(context.Background(), address)
+}
+
+func (f *Fake) GetAddressTransactionsInMemPoolCtx(ctx context.Context, address pkg.Address) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAddress(address), true, false, "")
+}
+
+func (f *Fake) GetScriptHashTransactionsInMemPool(hash pkg.ScriptHash) ([]*pkg.Transaction, error) {
+	return f.GetScriptHashTransactionsInMemPoolCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetScriptHashTransactionsInMemPoolCtx(ctx context.Context, hash pkg.ScriptHash) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchScriptHash(hash), true, false, "")
+}
+
+func (f *Fake) GetAddressUnspentTxOutputs(address pkg.Address) ([]*pkg.UnspentTransactionOutput, error) {
+	return f.GetAddressUnspentTxOutputsCtx(context.Background(), address)
+}
+
+func (f *Fake) GetAddressUnspentTxOutputsCtx(ctx context.Context, address pkg.Address) ([]*pkg.UnspentTransactionOutput, error) {
+	return f.unspent(ctx, matchAddress(address))
+}
+
+func (f *Fake) GetScriptHashUnspentTxOutputs(hash pkg.ScriptHash) ([]*pkg.UnspentTransactionOutput, error) {
+	return f.GetScriptHashUnspentTxOutputsCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetScriptHashUnspentTxOutputsCtx(ctx context.Context, hash pkg.ScriptHash) ([]*pkg.UnspentTransactionOutput, error) {
+	return f.unspent(ctx, matchScriptHash(hash))
+}
+
+func (f *Fake) GetBlock(hash pkg.BlockHash) (*pkg.Block, error) {
+	return f.GetBlockCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetBlockCtx(ctx context.Context, hash pkg.BlockHash) (*pkg.Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	block, ok := f.blocks[hash]
+	if !ok {
+		return nil, blockNotFound(fmt.Sprintf("/block/%s", hash))
+	}
+	return copyBlock(block), nil
+}
+
+// GetBlockHeader serializes the header from the seeded block fields.
+func (f *Fake) GetBlockHeader(hash pkg.BlockHash) (pkg.BlockHeaderHex, error) {
+	return f.GetBlockHeaderCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetBlockHeaderCtx(ctx context.Context, hash pkg.BlockHash) (pkg.BlockHeaderHex, error) {
+	block, err := f.GetBlockCtx(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	header, err := pkg.NewBlockHeader(block)
+	if err != nil {
+		return "", &pkg.APIError{StatusCode: http.StatusInternalServerError, Path: fmt.Sprintf("/block/%s/header", hash), Body: err.Error()}
+	}
+	return header.Hex(), nil
+}
+
+// GetRawBlock serializes the seeded header followed by the transactions
+// of the block, which must all have been given a hex with
+// SetTransactionHex.
+func (f *Fake) GetRawBlock(hash pkg.BlockHash) (io.ReadCloser, error) {
+	return f.GetRawBlockCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetRawBlockCtx(ctx context.Context, hash pkg.BlockHash) (io.ReadCloser, error) {
+	headerHex, err := f.GetBlockHeaderCtx(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	uri := fmt.Sprintf("/block/%s/raw", hash)
+	txIDs := f.blockTxIDs[hash]
+	raw, _ := hex.DecodeString(string(headerHex))
+	raw = append(raw, compactSize(uint64(len(txIDs)))...)
+	for _, txID := range txIDs {
+		txRaw, err := hex.DecodeString(string(f.hexes[txID]))
+		if err != nil || len(txRaw) == 0 {
+			return nil, &pkg.APIError{StatusCode: http.StatusInternalServerError, Path: uri, Body: fmt.Sprintf("no hex for %s", txID)}
+		}
+		raw = append(raw, txRaw...)
+	}
+	return ioutil.NopCloser(bytes.NewReader(raw)), nil
+}
+
+func (f *Fake) GetBlockStatus(hash pkg.BlockHash) (*pkg.BlockStatus, error) {
+	return f.GetBlockStatusCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetBlockStatusCtx(ctx context.Context, hash pkg.BlockHash) (*pkg.BlockStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	block, ok := f.blocks[hash]
+	if !ok || f.heights[block.Height] != hash {
+		return &pkg.BlockStatus{InBestChain: false}, nil
+	}
+	return &pkg.BlockStatus{InBestChain: true, Height: block.Height, NextBest: f.heights[block.Height+1]}, nil
+}
+
+func (f *Fake) GetBlockTransactions(hash pkg.BlockHash, startIndex int32) ([]*pkg.Transaction, error) {
+	return f.GetBlockTransactionsCtx(context.Background(), hash, startIndex)
+}
+
+func (f *Fake) GetBlockTransactionsCtx(ctx context.Context, hash pkg.BlockHash, startIndex int32) ([]*pkg.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("/block/%s/txs/%d", hash, startIndex)
+	txIDs, ok := f.blockTxIDs[hash]
+	if !ok {
+		return nil, blockNotFound(uri)
+	}
+	if startIndex < 0 || startIndex%blockTxsPageSize != 0 {
+		return nil, &pkg.APIError{StatusCode: http.StatusBadRequest, Path: uri, Body: "start index must be a multiple of 25"}
+	}
+	if int(startIndex) >= len(txIDs) {
+		return nil, &pkg.APIError{StatusCode: http.StatusNotFound, Path: uri, Body: "start index out of range"}
+	}
+
+	end := int(startIndex) + blockTxsPageSize
+	if end > len(txIDs) {
+		end = len(txIDs)
+	}
+	transactions := make([]*pkg.Transaction, 0, end-int(startIndex))
+	for _, txID := range txIDs[startIndex:end] {
+		transactions = append(transactions, copyTransaction(f.txs[txID]))
+	}
+	return transactions, nil
+}
+
+func (f *Fake) GetBlockTxIDs(hash pkg.BlockHash) ([]pkg.TxID, error) {
+	return f.GetBlockTxIDsCtx(context.Background(), hash)
+}
+
+func (f *Fake) GetBlockTxIDsCtx(ctx context.Context, hash pkg.BlockHash) ([]pkg.TxID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	txIDs, ok := f.blockTxIDs[hash]
+	if !ok {
+		return nil, blockNotFound(fmt.Sprintf("/block/%s/txids", hash))
+	}
+	return append([]pkg.TxID(nil), txIDs...), nil
+}
+
+func (f *Fake) GetBlockTxID(hash pkg.BlockHash, index int32) (pkg.TxID, error) {
+	return f.GetBlockTxIDCtx(context.Background(), hash, index)
+}
+
+func (f *Fake) GetBlockTxIDCtx(ctx context.Context, hash pkg.BlockHash, index int32) (pkg.TxID, error) {
+	txIDs, err := f.GetBlockTxIDsCtx(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if index < 0 || int(index) >= len(txIDs) {
+		return "", &pkg.APIError{StatusCode: http.StatusNotFound, Path: fmt.Sprintf("/block/%s/txid/%d", hash, index), Body: "tx index out of range"}
+	}
+	return txIDs[index], nil
+}
+
+func (f *Fake) GetBlockHash(height pkg.BlockHeight) (pkg.BlockHash, error) {
+	return f.GetBlockHashCtx(context.Background(), height)
+}
+
+func (f *Fake) GetBlockHashCtx(ctx context.Context, height pkg.BlockHeight) (pkg.BlockHash, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return "", err
+	}
+
+	hash, ok := f.heights[height]
+	if !ok {
+		return "", blockNotFound(fmt.Sprintf("/block-height/%d", height))
+	}
+	return hash, nil
+}
+
+func (f *Fake) GetBlocks(height pkg.BlockHeight) (pkg.Blocks, error) {
+	return f.GetBlocksCtx(context.Background(), height)
+}
+
+func (f *Fake) GetBlocksCtx(ctx context.Context, height pkg.BlockHeight) (pkg.Blocks, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	blocks := make(pkg.Blocks, 0, blocksPageSize)
+	for h := height; h >= 0 && len(blocks) < blocksPageSize; h-- {
+		if hash, ok := f.heights[h]; ok {
+			blocks = append(blocks, copyBlock(f.blocks[hash]))
+		}
+	}
+	sort.Sort(blocks)
+	return blocks, nil
+}
+
+func (f *Fake) GetLastBlockHeight() (pkg.BlockHeight, error) {
+	return f.GetLastBlockHeightCtx(context.Background())
+}
+
+func (f *Fake) GetLastBlockHeightCtx(ctx context.Context) (pkg.BlockHeight, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return 0, err
+	}
+
+	height, ok := f.tip()
+	if !ok {
+		return 0, blockNotFound("/blocks/tip/height")
+	}
+	return height, nil
+}
+
+func (f *Fake) GetLastBlockHash() (pkg.BlockHash, error) {
+	return f.GetLastBlockHashCtx(context.Background())
+}
+
+func (f *Fake) GetLastBlockHashCtx(ctx context.Context) (pkg.BlockHash, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return "", err
+	}
+
+	height, ok := f.tip()
+	if !ok {
+		return "", blockNotFound("/blocks/tip/hash")
+	}
+	return f.heights[height], nil
+}
+
+func (f *Fake) GetMemPoolStatistics() (*pkg.MemPoolStatistics, error) {
+	return f.GetMemPoolStatisticsCtx(context.Background())
+}
+
+func (f *Fake) GetMemPoolStatisticsCtx(ctx context.Context) (*pkg.MemPoolStatistics, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	statistics := &pkg.MemPoolStatistics{Count: int32(len(f.mempool))}
+	txs := make([]*pkg.Transaction, 0, len(f.mempool))
+	for _, txID := range f.mempool {
+		tx := f.txs[txID]
+		statistics.VSize += vSize(tx)
+		statistics.TotalFee += tx.Fee
+		txs = append(txs, tx)
+	}
+	statistics.FeeHistogram = feeHistogram(txs)
+	return statistics, nil
+}
+
+// feeHistogram bins txs as electrs does: highest fee rate first, closing a
+// bucket once it holds more than histogramBinVSize vbytes and the rate
+// changes.
+func feeHistogram(txs []*pkg.Transaction) pkg.FeeHistogram {
+	rate := func(tx *pkg.Transaction) pkg.FeeRate {
+		if vSize(tx) == 0 {
+			return 0
+		}
+		return pkg.FeeRate(float64(tx.Fee) / float64(vSize(tx)))
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return rate(txs[i]) > rate(txs[j]) })
+
+	histogram := make(pkg.FeeHistogram, 0)
+	var bucket pkg.FeeBucket
+	for _, tx := range txs {
+		if bucket.VSize > histogramBinVSize && bucket.FeeRate != rate(tx) {
+			histogram = append(histogram, bucket)
+			bucket.VSize = 0
+		}
+		bucket.FeeRate = rate(tx)
+		bucket.VSize += int64(vSize(tx))
+	}
+	if bucket.VSize > 0 {
+		histogram = append(histogram, bucket)
+	}
+	return histogram
+}
+
+func (f *Fake) GetMemPoolTxIDs() ([]pkg.TxID, error) {
+	return f.GetMemPoolTxIDsCtx(context.Background())
+}
+
+func (f *Fake) GetMemPoolTxIDsCtx(ctx context.Context) ([]pkg.TxID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+	return append([]pkg.TxID(nil), f.mempool...), nil
+}
+
+func (f *Fake) GetMemPoolRecentOverviews() ([]*pkg.MemPoolOverviewData, error) {
+	return f.GetMemPoolRecentOverviewsCtx(context.Background())
+}
+
+func (f *Fake) GetMemPoolRecentOverviewsCtx(ctx context.Context) ([]*pkg.MemPoolOverviewData, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	overviews := make([]*pkg.MemPoolOverviewData, 0, recentMemPoolTxCount)
+	for i := len(f.mempool) - 1; i >= 0 && len(overviews) < recentMemPoolTxCount; i-- {
+		tx := f.txs[f.mempool[i]]
+		overview := &pkg.MemPoolOverviewData{ID: tx.ID, Fee: tx.Fee, VSize: vSize(tx)}
+		for _, out := range tx.VOut {
+			overview.Value += out.Value
+		}
+		overviews = append(overviews, overview)
+	}
+	return overviews, nil
+}
+
+func (f *Fake) GetFeeEstimates() (*pkg.FeeEstimates, error) {
+	return f.GetFeeEstimatesCtx(context.Background())
+}
+
+func (f *Fake) GetFeeEstimatesCtx(ctx context.Context) (*pkg.FeeEstimates, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	fees := copyFeeEstimates(f.fees)
+	return &fees, nil
+}
+
+func (f *Fake) GetAsset(assetID pkg.AssetID) (*pkg.Asset, error) {
+	return f.GetAssetCtx(context.Background(), assetID)
+}
+
+func (f *Fake) GetAssetCtx(ctx context.Context, assetID pkg.AssetID) (*pkg.Asset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	asset, ok := f.assets[assetID]
+	if !ok {
+		return nil, assetNotFound(fmt.Sprintf("/asset/%s", assetID))
+	}
+	return copyAsset(asset), nil
+}
+
+func (f *Fake) GetAssetTransactions(assetID pkg.AssetID) ([]*pkg.Transaction, error) {
+	return f.GetAssetTransactionsCtx(context.Background(), assetID)
+}
+
+func (f *Fake) GetAssetTransactionsCtx(ctx context.Context, assetID pkg.AssetID) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAsset(assetID), true, true, "")
+}
+
+func (f *Fake) GetAssetTransactionsLatest(assetID pkg.AssetID, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.GetAssetTransactionsLatestCtx(context.Background(), assetID, lastTxID)
+}
+
+func (f *Fake) GetAssetTransactionsLatestCtx(ctx context.Context, assetID pkg.AssetID, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAsset(assetID), false, true, lastTxID)
+}
+
+func (f *Fake) GetAssetTransactionsInMemPool(assetID pkg.AssetID) ([]*pkg.Transaction, error) {
+	return f.GetAssetTransactionsInMemPoolCtx(context.Background(), assetID)
+}
+
+func (f *Fake) GetAssetTransactionsInMemPoolCtx(ctx context.Context, assetID pkg.AssetID) ([]*pkg.Transaction, error) {
+	return f.history(ctx, matchAsset(assetID), true, false, "")
+}
+
+func (f *Fake) GetAssetSupply(assetID pkg.AssetID) (pkg.Satoshi, error) {
+	return f.GetAssetSupplyCtx(context.Background(), assetID)
+}
+
+func (f *Fake) GetAssetSupplyCtx(ctx context.Context, assetID pkg.AssetID) (pkg.Satoshi, error) {
+	asset, err := f.GetAssetCtx(ctx, assetID)
+	if err != nil {
+		return 0, err
+	}
+	return asset.Supply()
+}
+
+func (f *Fake) GetAssetSupplyDecimal(assetID pkg.AssetID) (string, error) {
+	return f.GetAssetSupplyDecimalCtx(context.Background(), assetID)
+}
+
+func (f *Fake) GetAssetSupplyDecimalCtx(ctx context.Context, assetID pkg.AssetID) (string, error) {
+	asset, err := f.GetAssetCtx(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	supply, err := asset.Supply()
+	if err != nil {
+		return "", err
+	}
+	return asset.FormatAmount(supply), nil
+}
+
+func (f *Fake) GetAssetRegistry(query pkg.AssetRegistryQuery) ([]*pkg.Asset, int, error) {
+	return f.GetAssetRegistryCtx(context.Background(), query)
+}
+
+func (f *Fake) GetAssetRegistryCtx(ctx context.Context, query pkg.AssetRegistryQuery) ([]*pkg.Asset, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	registry := make([]*pkg.Asset, 0)
+	for _, asset := range f.assets {
+		if asset.Name != "" {
+			registry = append(registry, asset)
+		}
+	}
+	key := func(asset *pkg.Asset) string {
+		switch query.SortField {
+		case pkg.AssetSortByName:
+			return asset.Name
+		case pkg.AssetSortByDomain:
+			if asset.Entity != nil {
+				return asset.Entity.Domain
+			}
+			return ""
+		}
+		return asset.Ticker
+	}
+	sort.Slice(registry, func(i, j int) bool {
+		ki, kj := key(registry[i]), key(registry[j])
+		if ki == kj {
+			return registry[i].ID < registry[j].ID
+		}
+		return (ki < kj) != query.Descending
+	})
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = registryPageSize
+	}
+	start := query.StartIndex
+	if start > len(registry) {
+		start = len(registry)
+	}
+	end := start + limit
+	if end > len(registry) {
+		end = len(registry)
+	}
+	page := make([]*pkg.Asset, 0, end-start)
+	for _, asset := range registry[start:end] {
+		page = append(page, copyAsset(asset))
+	}
+	return page, len(registry), nil
+}
+
+func (f *Fake) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("request canceled: %w", err)
+	}
+	return f.err
+}
+
+func (f *Fake) addTransaction(tx *pkg.Transaction) {
+	if _, ok := f.seq[tx.ID]; !ok {
+		f.seq[tx.ID] = f.nextSeq
+		f.nextSeq++
+	}
+	f.txs[tx.ID] = tx
+	f.removeFromMempool(tx.ID)
+	if !tx.Status.Confirmed {
+		f.mempool = append(f.mempool, tx.ID)
+	}
+}
+
+func (f *Fake) removeFromMempool(txID pkg.TxID) {
+	for i, id := range f.mempool {
+		if id == txID {
+			f.mempool = append(f.mempool[:i], f.mempool[i+1:]...)
+			return
+		}
+	}
+}
+
+// disconnect returns the transactions of a replaced block to the mempool.
+func (f *Fake) disconnect(hash pkg.BlockHash) {
+	for _, txID := range f.blockTxIDs[hash] {
+		if tx, ok := f.txs[txID]; ok && tx.Status.BlockHash == string(hash) {
+			tx.Status = pkg.TransactionStatus{}
+			f.mempool = append(f.mempool, txID)
+		}
+	}
+}
+
+func (f *Fake) tip() (pkg.BlockHeight, bool) {
+	var tip pkg.BlockHeight
+	found := false
+	for height := range f.heights {
+		if !found || height > tip {
+			tip, found = height, true
+		}
+	}
+	return tip, found
+}
+
+func (f *Fake) outSpend(txID pkg.TxID, vOut int32) *pkg.TransactionOutSpend {
+	for _, tx := range f.txs {
+		for pos, in := range tx.VIn {
+			if in.ID == txID && in.VOut == int64(vOut) {
+				status := tx.Status
+				return &pkg.TransactionOutSpend{Spent: true, ID: tx.ID, VInPos: int32(pos), Status: &status}
+			}
+		}
+	}
+	return &pkg.TransactionOutSpend{Spent: false}
+}
+
+type outputMatcher func(out *pkg.TransactionOut) bool
+
+func matchAddress(address pkg.Address) outputMatcher {
+	return func(out *pkg.TransactionOut) bool {
+		return out.ScriptPubKeyAddress != "" && pkg.Address(out.ScriptPubKeyAddress) == address
+	}
+}
+
+func matchAsset(assetID pkg.AssetID) outputMatcher {
+	return func(out *pkg.TransactionOut) bool {
+		return assetID != "" && out.Asset == assetID
+	}
+}
+
+func matchScriptHash(hash pkg.ScriptHash) outputMatcher {
+	return func(out *pkg.TransactionOut) bool {
+		script, err := hex.DecodeString(out.ScriptPubKey)
+		return err == nil && pkg.ScriptHashFromScript(script) == hash
+	}
+}
+
+func (f *Fake) touches(tx *pkg.Transaction, match outputMatcher) bool {
+	for _, out := range tx.VOut {
+		if match(out) {
+			return true
+		}
+	}
+	for _, in := range tx.VIn {
+		if match(&in.PrevOut) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fake) stats(match outputMatcher) (pkg.ChainStats, pkg.ChainStats) {
+	var chainStats, memStats pkg.ChainStats
+	for _, tx := range f.txs {
+		if !f.touches(tx, match) {
+			continue
+		}
+		stats := &memStats
+		if tx.Status.Confirmed {
+			stats = &chainStats
+		}
+		stats.TxCount++
+		for _, out := range tx.VOut {
+			if match(out) {
+				stats.FoundedTxoCount++
+				stats.FoundedTxoSum += out.Value
+			}
+		}
+		for _, in := range tx.VIn {
+			if match(&in.PrevOut) {
+				stats.SpentTxoCount++
+				stats.SpentTxoSum += in.PrevOut.Value
+			}
+		}
+	}
+	return chainStats, memStats
+}
+
+// history mirrors electrs paging: up to 50 mempool transactions, newest
+// first, followed by pages of 25 confirmed transactions ordered by height
+// descending and resumed after lastTxID.
+func (f *Fake) history(ctx context.Context, match outputMatcher, mempool, chain bool, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	var unconfirmed, confirmed []*pkg.Transaction
+	for _, tx := range f.txs {
+		if !f.touches(tx, match) {
+			continue
+		}
+		if tx.Status.Confirmed {
+			confirmed = append(confirmed, tx)
+		} else {
+			unconfirmed = append(unconfirmed, tx)
+		}
+	}
+	f.sortNewestFirst(unconfirmed)
+	f.sortNewestFirst(confirmed)
+
+	transactions := make([]*pkg.Transaction, 0)
+	if mempool {
+		if len(unconfirmed) > mempoolTxsPageSize {
+			unconfirmed = unconfirmed[:mempoolTxsPageSize]
+		}
+		for _, tx := range unconfirmed {
+			transactions = append(transactions, copyTransaction(tx))
+		}
+	}
+	if chain {
+		start := 0
+		if lastTxID != "" {
+			start = len(confirmed)
+			for i, tx := range confirmed {
+				if tx.ID == lastTxID {
+					start = i + 1
+					break
+				}
+			}
+		}
+		end := start + chainTxsPageSize
+		if end > len(confirmed) {
+			end = len(confirmed)
+		}
+		for _, tx := range confirmed[start:end] {
+			transactions = append(transactions, copyTransaction(tx))
+		}
+	}
+	return transactions, nil
+}
+
+func (f *Fake) sortNewestFirst(txs []*pkg.Transaction) {
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Status.BlockHeight != txs[j].Status.BlockHeight {
+			return txs[i].Status.BlockHeight > txs[j].Status.BlockHeight
+		}
+		return f.seq[txs[i].ID] > f.seq[txs[j].ID]
+	})
+}
+
+func (f *Fake) unspent(ctx context.Context, match outputMatcher) ([]*pkg.UnspentTransactionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx); err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]bool)
+	for _, tx := range f.txs {
+		for _, in := range tx.VIn {
+			spent[outPoint(in.ID, in.VOut)] = true
+		}
+	}
+
+	outputs := make([]*pkg.UnspentTransactionOutput, 0)
+	for _, tx := range f.txs {
+		for vOut, out := range tx.VOut {
+			if match(out) && !spent[outPoint(tx.ID, int64(vOut))] {
+				outputs = append(outputs, &pkg.UnspentTransactionOutput{
+					ID:              tx.ID,
+					VOut:            int32(vOut),
+					Status:          tx.Status,
+					Value:           out.Value,
+					ValueCommitment: out.ValueCommitment,
+					Asset:           out.Asset,
+					AssetCommitment: out.AssetCommitment,
+				})
+			}
+		}
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].ID != outputs[j].ID {
+			return f.seq[outputs[i].ID] < f.seq[outputs[j].ID]
+		}
+		return outputs[i].VOut < outputs[j].VOut
+	})
+	return outputs, nil
+}
+
+func copyTransaction(tx *pkg.Transaction) *pkg.Transaction {
+	c := *tx
+	c.VIn = make([]*pkg.TransactionIn, 0, len(tx.VIn))
+	for _, in := range tx.VIn {
+		in := *in
+		in.PrevOut = copyOutput(in.PrevOut)
+		in.Witness = append([]string(nil), in.Witness...)
+		if in.Issuance != nil {
+			issuance := *in.Issuance
+			in.Issuance = &issuance
+		}
+		c.VIn = append(c.VIn, &in)
+	}
+	c.VOut = make([]*pkg.TransactionOut, 0, len(tx.VOut))
+	for _, out := range tx.VOut {
+		out := copyOutput(*out)
+		c.VOut = append(c.VOut, &out)
+	}
+	return &c
+}
+
+func copyOutput(out pkg.TransactionOut) pkg.TransactionOut {
+	if out.Pegout != nil {
+		pegout := *out.Pegout
+		out.Pegout = &pegout
+	}
+	return out
+}
+
+func copyBlock(block *pkg.Block) *pkg.Block {
+	c := *block
+	c.Ext = append([]byte(nil), block.Ext...)
+	return &c
+}
+
+func copyFeeEstimates(fees pkg.FeeEstimates) pkg.FeeEstimates {
+	c := make(pkg.FeeEstimates, len(fees))
+	for target, rate := range fees {
+		c[target] = rate
+	}
+	return c
+}
+
+func copyAsset(asset *pkg.Asset) *pkg.Asset {
+	c := *asset
+	if asset.IssuanceTxIn != nil {
+		txIn := *asset.IssuanceTxIn
+		c.IssuanceTxIn = &txIn
+	}
+	if asset.IssuancePrevOut != nil {
+		prevOut := *asset.IssuancePrevOut
+		c.IssuancePrevOut = &prevOut
+	}
+	if asset.Status != nil {
+		status := *asset.Status
+		c.Status = &status
+	}
+	if asset.Entity != nil {
+		entity := *asset.Entity
+		c.Entity = &entity
+	}
+	c.Contract = append([]byte(nil), asset.Contract...)
+	return &c
+}
+
+func outPoint(txID pkg.TxID, vOut int64) string {
+	return string(txID) + ":" + strconv.FormatInt(vOut, 10)
+}
+
+func vSize(tx *pkg.Transaction) int32 {
+	return (tx.Weight + 3) / 4
+}
+
+func txNotFound(uri string) error {
+	return &pkg.APIError{StatusCode: http.StatusNotFound, Path: uri, Body: "Transaction not found"}
+}
+
+func assetNotFound(uri string) error {
+	return &pkg.APIError{StatusCode: http.StatusNotFound, Path: uri, Body: "Asset id not found"}
+}
+
+func blockNotFound(uri string) error {
+	return &pkg.APIError{StatusCode: http.StatusNotFound, Path: uri, Body: "Block not found"}
+}
+
+func compactSize(n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return []byte{byte(n)}
+	case n <= 0xffff:
+		return []byte{0xfd, byte(n), byte(n >> 8)}
+	case n <= 0xffffffff:
+		return []byte{0xfe, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+	}
+	return []byte{0xff, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24), byte(n >> 32), byte(n >> 40), byte(n >> 48), byte(n >> 56)}
+}
+
+// merkleBranch returns the sibling hashes proving txIDs[pos], in the
+// displayed (reversed) byte order used by electrs.
+func merkleBranch(txIDs []pkg.TxID, pos int) ([]string, error) {
+	level := make([][]byte, 0, len(txIDs))
+	for _, txID := range txIDs {
+		raw, err := hex.DecodeString(string(txID))
+		if err != nil {
+			return nil, err
+		}
+		level = append(level, reversed(raw))
+	}
+
+	branch := make([]string, 0)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, hex.EncodeToString(reversed(level[pos^1])))
+
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, doubleSHA256(append(append([]byte{}, level[i]...), level[i+1]...)))
+		}
+		level = next
+		pos /= 2
+	}
+	return branch, nil
+}
+
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func reversed(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
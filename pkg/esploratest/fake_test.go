@@ -0,0 +1,305 @@
+package esploratest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"reflect"
+	"testing"
+)
+
+const (
+	address     = pkg.Address("bc1qtestaddress")
+	fundingTxID = pkg.TxID("1111111111111111111111111111111111111111111111111111111111111111")
+	spendTxID   = pkg.TxID("2222222222222222222222222222222222222222222222222222222222222222")
+	coinbaseID  = pkg.TxID("3333333333333333333333333333333333333333333333333333333333333333")
+	blockHash   = pkg.BlockHash("00000000000000000000000000000000000000000000000000000000000000aa")
+)
+
+func seed() *Fake {
+	fake := New()
+	funding := &pkg.Transaction{
+		ID:     fundingTxID,
+		Weight: 400,
+		Fee:    100,
+		VOut:   []*pkg.TransactionOut{{ScriptPubKey: "0014aa", ScriptPubKeyAddress: string(address), Value: 5000}},
+	}
+	coinbase := &pkg.Transaction{ID: coinbaseID, VOut: []*pkg.TransactionOut{{Value: 625000000}}}
+	fake.AddBlock(&pkg.Block{ID: blockHash, Height: 100, Timestamp: 1600000000}, coinbase, funding)
+
+	spend := &pkg.Transaction{
+		ID:     spendTxID,
+		Weight: 600,
+		Fee:    200,
+		VIn:    []*pkg.TransactionIn{{ID: fundingTxID, VOut: 0, PrevOut: *funding.VOut[0]}},
+		VOut:   []*pkg.TransactionOut{{ScriptPubKey: "0014bb", ScriptPubKeyAddress: "bc1qother", Value: 4800}},
+	}
+	fake.AddTransaction(spend)
+	return fake
+}
+
+func TestFake_AddressInfo(t *testing.T) {
+	info, err := seed().GetAddressInfo(address)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if info.ChainStats.TxCount != 1 || info.ChainStats.FoundedTxoSum != 5000 {
+		t.Errorf("invalid chain stats %+v", info.ChainStats)
+	}
+	if info.MemStats.TxCount != 1 || info.MemStats.SpentTxoSum != 5000 {
+		t.Errorf("invalid mem stats %+v", info.MemStats)
+	}
+}
+
+func TestFake_AddressTransactions(t *testing.T) {
+	fake := seed()
+	transactions, err := fake.GetAddressTransactions(address)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 2 || transactions[0].ID != spendTxID || transactions[1].ID != fundingTxID {
+		t.Errorf("invalid transactions")
+	}
+
+	mempool, err := fake.GetAddressTransactionsInMemPool(address)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(mempool) != 1 || mempool[0].ID != spendTxID {
+		t.Errorf("invalid mempool transactions")
+	}
+
+	outputs, err := fake.GetAddressUnspentTxOutputs(address)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(outputs) != 0 {
+		t.Errorf("spent output reported as unspent")
+	}
+}
+
+func TestFake_ScriptHash(t *testing.T) {
+	// sha256(0x0014aa), reversed
+	hash := pkg.ScriptHash("940264f5762eac1844db3534353da346eec79b1e2cb6a073854bced3948e0284")
+	fake := seed()
+
+	info, err := fake.GetScriptHashInfo(hash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if info.ChainStats.TxCount != 1 {
+		t.Errorf("invalid scripthash info %+v", info)
+	}
+}
+
+func TestFake_Blocks(t *testing.T) {
+	fake := seed()
+
+	height, err := fake.GetLastBlockHeight()
+	if err != nil || height != 100 {
+		t.Fatalf("invalid tip %d: %v", height, err)
+	}
+
+	txID, err := fake.GetBlockTxID(blockHash, 1)
+	if err != nil || txID != fundingTxID {
+		t.Fatalf("invalid block txid %s: %v", txID, err)
+	}
+
+	proof, err := fake.GetTransactionMerkleProof(fundingTxID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if proof.Pos != 1 || len(proof.Merkle) != 1 || proof.Merkle[0] != string(coinbaseID) {
+		t.Errorf("invalid merkle proof %+v", proof)
+	}
+
+	outSpend, err := fake.GetTransactionOutSpend(fundingTxID, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !outSpend.Spent || outSpend.ID != spendTxID {
+		t.Errorf("invalid outspend %+v", outSpend)
+	}
+}
+
+func TestFake_Mempool(t *testing.T) {
+	statistics, err := seed().GetMemPoolStatistics()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if statistics.Count != 1 || statistics.VSize != 150 || statistics.TotalFee != 200 {
+		t.Errorf("invalid mempool statistics %+v", statistics)
+	}
+}
+
+func TestFake_FeeHistogram(t *testing.T) {
+	fake := seed()
+	for i, fee := range []pkg.Satoshi{600000, 600000, 300000} {
+		fake.AddTransaction(&pkg.Transaction{ID: pkg.TxID(fmt.Sprintf("%064x", i+10)), Weight: 240000, Fee: fee})
+	}
+	statistics, err := fake.GetMemPoolStatistics()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// The 5 sat/vB bucket stays open below 100000 vbytes and absorbs the
+	// seeded spend, taking its rate.
+	expected := pkg.FeeHistogram{{FeeRate: 10, VSize: 120000}, {FeeRate: pkg.FeeRate(200.0 / 150), VSize: 60150}}
+	if !reflect.DeepEqual(statistics.FeeHistogram, expected) {
+		t.Errorf("unexpected fee histogram %v", statistics.FeeHistogram)
+	}
+}
+
+func TestFake_Errors(t *testing.T) {
+	fake := seed()
+
+	if _, err := fake.GetTransaction("00"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fake.GetTransactionCtx(ctx, fundingTxID); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	fake.SetError(&pkg.APIError{StatusCode: 503})
+	if _, err := fake.GetLastBlockHash(); !errors.Is(err, pkg.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestFake_Broadcast(t *testing.T) {
+	fake := New()
+	if _, err := fake.BroadcastTransaction("zz"); !errors.Is(err, pkg.ErrTxDecodeFailed) {
+		t.Errorf("expected ErrTxDecodeFailed, got %v", err)
+	}
+
+	fake.OnBroadcast = func(txHex pkg.TxHex) (pkg.TxID, error) { return fundingTxID, nil }
+	txID, err := fake.BroadcastTransaction("0200")
+	if err != nil || txID != fundingTxID || len(fake.Broadcasted()) != 1 {
+		t.Errorf("invalid broadcast %s: %v", txID, err)
+	}
+}
+
+func TestFake_AddressHistory(t *testing.T) {
+	transactions, err := pkg.NewAddressHistory(context.Background(), seed(), address, pkg.IncludeMempool()).All()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 2 || transactions[0].ID != spendTxID || transactions[1].ID != fundingTxID {
+		t.Errorf("invalid history")
+	}
+}
+
+func TestFake_BlockHeader(t *testing.T) {
+	fake := New()
+	fake.AddBlock(&pkg.Block{
+		ID:                "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506",
+		Height:            100000,
+		Version:           1,
+		PreviousBlockHash: "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+		MerkleRoot:        "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		Timestamp:         1293623863,
+		Bits:              0x1b04864c,
+		Nonce:             274148111,
+	})
+
+	header, err := pkg.GetVerifiedBlockHeader(context.Background(), fake, "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if header.Nonce != 274148111 {
+		t.Errorf("unexpected header %+v", header)
+	}
+}
+
+func TestFake_Assets(t *testing.T) {
+	fake := seed()
+	asset := &pkg.Asset{
+		ID:           "aa",
+		IssuanceTxIn: &pkg.AssetTxIn{ID: fundingTxID},
+		ChainStats:   pkg.AssetStats{IssuedAmount: 1000, BurnedAmount: 1},
+		Name:         "Zeta",
+		Ticker:       "ZZ",
+		Precision:    2,
+	}
+	fake.AddAsset(asset)
+	fake.AddAsset(&pkg.Asset{ID: "bb", IssuanceTxIn: &pkg.AssetTxIn{}, Name: "Alpha", Ticker: "AA"})
+	fake.AddAsset(&pkg.Asset{ID: pkg.LiquidBTC})
+	fake.AddTransaction(&pkg.Transaction{
+		ID:   "4444444444444444444444444444444444444444444444444444444444444444",
+		VOut: []*pkg.TransactionOut{{ScriptPubKeyAddress: string(address), Value: 7, Asset: "aa"}, {ValueCommitment: "08aa"}},
+	})
+
+	if _, err := fake.GetAsset("cc"); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if supply, err := fake.GetAssetSupplyDecimal("aa"); err != nil || supply != "9.99" {
+		t.Errorf("unexpected supply %s %v", supply, err)
+	}
+	transactions, err := fake.GetAssetTransactionsInMemPool("aa")
+	if err != nil || len(transactions) != 1 {
+		t.Errorf("unexpected asset transactions %v %v", transactions, err)
+	}
+	utxos, err := fake.GetAddressUnspentTxOutputs(address)
+	if err != nil || len(utxos) != 1 || utxos[0].Asset != "aa" {
+		t.Errorf("unexpected unspent outputs %v %v", utxos, err)
+	}
+
+	registry, total, err := fake.GetAssetRegistry(pkg.AssetRegistryQuery{SortField: pkg.AssetSortByName, Limit: 1, StartIndex: 1})
+	if err != nil || total != 2 || len(registry) != 1 || registry[0].ID != asset.ID {
+		t.Errorf("unexpected registry %v %d %v", registry, total, err)
+	}
+}
+
+func TestFake_SearchAddressPrefix(t *testing.T) {
+	addresses, err := seed().SearchAddressPrefix("bc1q")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(addresses) != 2 || addresses[0] != "bc1qother" || addresses[1] != address {
+		t.Errorf("unexpected addresses %v", addresses)
+	}
+
+	results := pkg.GetAddressInfos(context.Background(), seed(), []pkg.Address{address, address, "bc1qother"})
+	if len(results) != 2 || results[address].Info.ChainStats.TxCount != 1 {
+		t.Errorf("unexpected results %v", results)
+	}
+}
+
+func TestFake_Copies(t *testing.T) {
+	fake := New()
+	tx := &pkg.Transaction{ID: fundingTxID, VOut: []*pkg.TransactionOut{{Value: 1}}}
+	block := &pkg.Block{ID: blockHash, Height: 1}
+	fake.AddBlock(block, tx)
+	if tx.Status.Confirmed || block.TxCount != 0 {
+		t.Errorf("AddBlock modified its arguments")
+	}
+
+	tx.VOut[0].Value = 2
+	got, err := fake.GetTransaction(fundingTxID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	got.VOut[0].Value = 3
+	got.Status.Confirmed = false
+	if again, _ := fake.GetTransaction(fundingTxID); again.VOut[0].Value != 1 || !again.Status.Confirmed {
+		t.Errorf("stored transaction shared with callers: %+v", again)
+	}
+
+	held, _ := fake.GetTransaction(fundingTxID)
+	fake.AddBlock(&pkg.Block{ID: "00000000000000000000000000000000000000000000000000000000000000bb", Height: 1})
+	if !held.Status.Confirmed || held.Status.BlockHash != string(blockHash) {
+		t.Errorf("disconnect modified a returned transaction: %+v", held.Status)
+	}
+
+	fees := pkg.FeeEstimates{"1": 20}
+	fake.SetFeeEstimates(fees)
+	fees["1"] = 1
+	returned, _ := fake.GetFeeEstimates()
+	(*returned)["1"] = 2
+	if again, _ := fake.GetFeeEstimates(); (*again)["1"] != 20 {
+		t.Errorf("fee estimates shared with callers: %v", *again)
+	}
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var ErrNoFeeEstimate = errors.New("no fee estimate")
+
+// FeeRate is a fee rate in sat/vB, the unit of /fee-estimates and the fee
+// histogram.
+type FeeRate float64
+
+// FeeRateFromSatPerKWU converts a rate in satoshis per 1000 weight units.
+func FeeRateFromSatPerKWU(satPerKWU float64) FeeRate {
+	return FeeRate(satPerKWU * 4 / 1000)
+}
+
+// FeeRateFromBTCPerKB converts a rate in BTC per 1000 vbytes, as returned
+// by the Electrum EstimateFee and RelayFee.
+func FeeRateFromBTCPerKB(btcPerKB float64) FeeRate {
+	return FeeRate(btcPerKB * float64(SatoshiPerBTC) / 1000)
+}
+
+func (r FeeRate) SatPerVByte() float64 {
+	return float64(r)
+}
+
+func (r FeeRate) SatPerKWU() float64 {
+	return float64(r) * 1000 / 4
+}
+
+// Fee returns the fee for vsize vbytes at r, rounded up.
+func (r FeeRate) Fee(vsize int64) Satoshi {
+	return Satoshi(math.Ceil(float64(r) * float64(vsize)))
+}
+
+// FeeBucket is a fee histogram entry: VSize vbytes of mempool transactions
+// pay at least FeeRate, and less than the previous bucket's rate.
+type FeeBucket struct {
+	FeeRate FeeRate
+	VSize   int64
+}
+
+// FeeHistogram is the mempool fee histogram, highest rate first. It is
+// encoded as [fee_rate, vsize] pairs.
+type FeeHistogram []FeeBucket
+
+func (h *FeeHistogram) UnmarshalJSON(data []byte) error {
+	pairs := make([][]float64, 0)
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	histogram := make(FeeHistogram, 0, len(pairs))
+	for i, pair := range pairs {
+		if len(pair) != 2 {
+			return fmt.Errorf("fee histogram entry %d: expected [fee_rate, vsize], got %v", i, pair)
+		}
+		histogram = append(histogram, FeeBucket{FeeRate: FeeRate(pair[0]), VSize: int64(pair[1])})
+	}
+	*h = histogram
+	return nil
+}
+
+func (h FeeHistogram) MarshalJSON() ([]byte, error) {
+	pairs := make([][2]float64, 0, len(h))
+	for _, bucket := range h {
+		pairs = append(pairs, [2]float64{float64(bucket.FeeRate), float64(bucket.VSize)})
+	}
+	return json.Marshal(pairs)
+}
+
+// Targets returns the confirmation targets with an estimate, ascending.
+func (f FeeEstimates) Targets() []int {
+	targets := make([]int, 0, len(f))
+	for key := range f {
+		if target, err := strconv.Atoi(key); err == nil && target > 0 {
+			targets = append(targets, target)
+		}
+	}
+	sort.Ints(targets)
+	return targets
+}
+
+// ForTarget returns the fee rate to confirm within blocks. Between two
+// known targets the rate is interpolated linearly; below the lowest and
+// above the highest the nearest estimate is used.
+func (f FeeEstimates) ForTarget(blocks int) (FeeRate, error) {
+	if blocks < 1 {
+		return 0, fmt.Errorf("%w: target %d blocks", ErrNoFeeEstimate, blocks)
+	}
+	targets := f.Targets()
+	if len(targets) == 0 {
+		return 0, ErrNoFeeEstimate
+	}
+
+	rate := func(target int) FeeRate { return FeeRate(f[strconv.Itoa(target)]) }
+	i := sort.SearchInts(targets, blocks)
+	switch {
+	case i == len(targets):
+		return rate(targets[len(targets)-1]), nil
+	case targets[i] == blocks || i == 0:
+		return rate(targets[i]), nil
+	}
+	lo, hi := targets[i-1], targets[i]
+	return rate(lo) + (rate(hi)-rate(lo))*FeeRate(blocks-lo)/FeeRate(hi-lo), nil
+}
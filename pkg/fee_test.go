@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The fee tests decode mempool.json and fee_estimates.json, which follow the
+// example responses of the Esplora API documentation rather than a capture
+// from a live server; the live build exercises the real endpoints.
+func readFixture(t *testing.T, name string, v interface{}) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestFeeHistogram_JSON(t *testing.T) {
+	var statistics MemPoolStatistics
+	readFixture(t, "mempool.json", &statistics)
+	histogram := statistics.FeeHistogram
+	if len(histogram) != 7 {
+		t.Fatalf("unexpected fee histogram %v", histogram)
+	}
+	if histogram[0] != (FeeBucket{FeeRate: 53.01, VSize: 102131}) || histogram[6] != (FeeBucket{FeeRate: 1.1, VSize: 775272}) {
+		t.Errorf("unexpected fee histogram %v", histogram)
+	}
+
+	data, err := json.Marshal(histogram)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var decoded FeeHistogram
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, histogram) {
+		t.Errorf("round trip failed: %s %v", data, err)
+	}
+	if data, _ := json.Marshal(FeeHistogram{}); string(data) != "[]" {
+		t.Errorf("unexpected empty histogram %s", data)
+	}
+
+	for _, data := range []string{`[[1.5]]`, `[[1,2,3]]`, `{"1":2}`, `[["1","2"]]`} {
+		if err := json.Unmarshal([]byte(data), &decoded); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
+
+func TestFeeEstimates_ForTarget(t *testing.T) {
+	var fees FeeEstimates
+	readFixture(t, "fee_estimates.json", &fees)
+
+	if targets := fees.Targets(); len(targets) != 15 || targets[0] != 1 || targets[14] != 1008 {
+		t.Errorf("unexpected targets %v", targets)
+	}
+	cases := map[int]FeeRate{
+		1:    87.882,
+		6:    68.285,
+		7:    68.285,
+		9:    60.6165,
+		11:   50.565,
+		1000: 1.027,
+		2000: 1.027,
+	}
+	for blocks, expected := range cases {
+		rate, err := fees.ForTarget(blocks)
+		if err != nil || math.Abs(float64(rate-expected)) > 1e-9 {
+			t.Errorf("%d blocks: expected %v, got %v %v", blocks, expected, rate, err)
+		}
+	}
+
+	if _, err := fees.ForTarget(0); !errors.Is(err, ErrNoFeeEstimate) {
+		t.Errorf("expected ErrNoFeeEstimate, got %v", err)
+	}
+	if _, err := (FeeEstimates{}).ForTarget(6); !errors.Is(err, ErrNoFeeEstimate) {
+		t.Errorf("expected ErrNoFeeEstimate, got %v", err)
+	}
+}
+
+func TestFeeRate_Units(t *testing.T) {
+	rate := FeeRate(87.882)
+	if rate.SatPerVByte() != 87.882 || math.Abs(rate.SatPerKWU()-21970.5) > 1e-9 {
+		t.Errorf("unexpected conversions %v %v", rate.SatPerVByte(), rate.SatPerKWU())
+	}
+	if fee := rate.Fee(141); fee != 12392 {
+		t.Errorf("unexpected fee %d", fee)
+	}
+	if r := FeeRateFromSatPerKWU(253); math.Abs(float64(r)-1.012) > 1e-9 {
+		t.Errorf("unexpected rate %v", r)
+	}
+	if r := FeeRateFromBTCPerKB(0.00001); math.Abs(float64(r)-1) > 1e-9 {
+		t.Errorf("unexpected rate %v", r)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build live
+// +build live
+
+package pkg
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRecordFixtures replaces the file fixtures with responses of the live
+// server, keeping the exchanges as Recorder golden files under
+// testdata/recorded. It only runs when asked to:
+//
+//	RECORD_FIXTURES=1 go test -tags live -run TestRecordFixtures ./pkg/
+//
+// Routes the server does not answer with 200, e.g. the Liquid asset routes
+// on a Bitcoin server, are reported and left as they are.
+func TestRecordFixtures(t *testing.T) {
+	if os.Getenv("RECORD_FIXTURES") == "" {
+		t.Skip("set RECORD_FIXTURES to record")
+	}
+	recorder := NewRecorder(filepath.Join("testdata", "recorded"), ModeRecord)
+	httpClient := &http.Client{Transport: recorder}
+
+	for path, route := range fixtureRoutes {
+		if route.file == "" {
+			continue
+		}
+		resp, err := httpClient.Get(liveHostUrl + path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err.Error())
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Errorf("%s: %s", path, err.Error())
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Logf("%s: status %d, %s left as is", path, resp.StatusCode, route.file)
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join("testdata", route.file), body, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	notFoundTxID    = "0000000000000000000000000000000000000000000000000000000000000000"
+	malformedTxID   = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	unavailableHash = "00000000000000000000000000000000000000000000000000000000deadbeef"
+	blindedAssetID  = "b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1"
+)
+
+type fixture struct {
+	status int
+	file   string
+	body   string
+}
+
+// fixtureRoutes maps every endpoint exercised by the tests to a canned
+// response under testdata/. The fixtures are hand-written in the format
+// electrs uses, not recordings: addresses, scripts, signatures and
+// timestamps are placeholders. Only block_raw.bin and tx_coinbase.json (the
+// genesis block and its coinbase) and block_header.txt (block 100000) are
+// real chain data. Agreement with a real server is checked by the live
+// build, see client_live_test.go, and TestRecordFixtures in
+// fixtures_live_test.go replaces the files with recorded responses.
+var fixtureRoutes = map[string]fixture{
+	"/tx/6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899":              {file: "tx.json"},
+	"/tx/6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899/status":       {file: "tx_status.json"},
+	"/tx/6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899/hex":          {file: "tx_hex.txt"},
+	"/tx/6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899/merkle-proof": {file: "tx_merkle_proof.json"},
+	"/tx/4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b":              {file: "tx_coinbase.json"},
+	"/tx/9a7ba47d71b2526b9f9a4376ea83c7afe4bf13cb0957a17148ce4adbb8eb47b0/outspend/1":   {file: "tx_outspend.json"},
+	"/tx/9a7ba47d71b2526b9f9a4376ea83c7afe4bf13cb0957a17148ce4adbb8eb47b0/outspends":    {file: "tx_outspends.json"},
+
+	"/address/152f1muMCNa7goXYhYAQC61hxEgGacmncB":     {file: "address.json"},
+	"/address/152f1muMCNa7goXYhYAQC61hxEgGacmncB/txs": {file: "address_txs.json"},
+	"/address/152f1muMCNa7goXYhYAQC61hxEgGacmncB/txs/chain/ae6077ec4bb4f3938eb204972d6dfaa6da74543af1ebaca666cce0453a0fe9a6": {file: "address_txs_chain.json"},
+	"/address/152f1muMCNa7goXYhYAQC61hxEgGacmncB/txs/mempool":                                                                {file: "address_txs_mempool.json"},
+	"/address/152f1muMCNa7goXYhYAQC61hxEgGacmncB/utxo":                                                                       {file: "address_utxo.json"},
+	"/scripthash/55c3e0412df763244b0fe23a5129cda6f606be45":                                                                   {file: "scripthash.json"},
+	"/scripthash/6fe28c0ab6f1b372c1a6a246ae63f74f931e8365e15a089c68d6190000000000/txs":                                       {file: "scripthash_txs.json"},
+	"/address-prefix/152f1mu": {file: "address_prefix.json"},
+
+	"/block/00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a":        {file: "block.json"},
+	"/block/00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a/status": {file: "block_status.json"},
+	"/block/00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a/txs/50": {file: "block_txs_50.json"},
+	"/block/00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a/txids":  {file: "block_txids.json"},
+	"/block/00000000000000000003efa46ef30fe654bca88081953f65b6ae217bceaec20a/txid/3": {file: "block_txid_3.txt"},
+	"/block/000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506/header": {file: "block_header.txt"},
+	"/block/000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f/raw":    {file: "block_raw.bin"},
+	"/block-height/49999": {file: "block_height_49999.txt"},
+	"/blocks/49999":       {file: "blocks_49999.json"},
+	"/blocks/tip/height":  {file: "blocks_tip_height.txt"},
+	"/blocks/tip/hash":    {file: "blocks_tip_hash.txt"},
+
+	"/mempool":        {file: "mempool.json"},
+	"/mempool/txids":  {file: "mempool_txids.json"},
+	"/mempool/recent": {file: "mempool_recent.json"},
+	"/fee-estimates":  {file: "fee_estimates.json"},
+
+	"/asset/ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2":                {file: "asset.json"},
+	"/asset/ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2/txs":            {file: "asset_txs.json"},
+	"/asset/ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2/supply":         {file: "asset_supply.txt"},
+	"/asset/ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2/supply/decimal": {file: "asset_supply_decimal.txt"},
+	"/asset/6f0279e9ed041c3d710a9f57d0c02928416460c4b722ae3457a11eec381c526d":                {file: "asset_native.json"},
+	"/asset/" + blindedAssetID + "/supply":                                                   {status: http.StatusBadRequest, body: "Asset supply is blinded"},
+
+	"/tx/" + notFoundTxID:       {status: http.StatusNotFound, body: "Transaction not found"},
+	"/tx/zz":                    {status: http.StatusBadRequest, body: "Invalid hex string"},
+	"/tx/" + malformedTxID:      {body: `{"txid":"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff","version":`},
+	"/block/" + unavailableHash: {status: http.StatusServiceUnavailable, body: "Service Unavailable"},
+}
+
+func newFixtureServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := fixtureRoutes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("fixture not found: " + r.URL.Path))
+			return
+		}
+
+		body := []byte(route.body)
+		if route.file != "" {
+			var err error
+			if body, err = ioutil.ReadFile(filepath.Join("testdata", route.file)); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(err.Error()))
+				return
+			}
+		}
+
+		if strings.HasSuffix(route.file, ".json") {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		if route.status != 0 {
+			w.WriteHeader(route.status)
+		}
+		_, _ = w.Write(body)
+	}))
+}
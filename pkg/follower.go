@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrReorgTooDeep = errors.New("reorg deeper than the configured maximum")
+
+// ErrInconsistentChain is returned by Sync when the server's next block does
+// not link to a tip the server still reports as part of the best chain.
+var ErrInconsistentChain = errors.New("server chain is inconsistent")
+
+// beforeGenesis is the checkpoint height preceding the genesis block.
+const beforeGenesis BlockHeight = -1
+
+type ChainEventType int
+
+const (
+	BlockConnected ChainEventType = iota
+	BlockDisconnected
+)
+
+func (t ChainEventType) String() string {
+	switch t {
+	case BlockConnected:
+		return "BlockConnected"
+	case BlockDisconnected:
+		return "BlockDisconnected"
+	}
+	return fmt.Sprintf("ChainEventType(%d)", int(t))
+}
+
+// ChainEvent reports a block joining or leaving the best chain. Checkpoint
+// is the follower position after the event and is what a consumer should
+// persist once it has processed the event.
+type ChainEvent struct {
+	Type       ChainEventType
+	Block      *Block
+	Checkpoint Checkpoint
+}
+
+// Checkpoint is a follower position. Height -1, with an empty Hash, is the
+// position before the genesis block.
+type Checkpoint struct {
+	Height BlockHeight `json:"height"`
+	Hash   BlockHash   `json:"hash"`
+}
+
+type FollowerOption func(*followerConfig)
+
+type followerConfig struct {
+	pollInterval  time.Duration
+	maxRetryDelay time.Duration
+	maxReorgDepth int
+	eventBuffer   int
+	onSyncError   func(error)
+	fromGenesis   bool
+}
+
+func PollInterval(interval time.Duration) FollowerOption {
+	return func(c *followerConfig) { c.pollInterval = interval }
+}
+
+// MaxRetryDelay caps the backoff of Run after failed syncs, which starts at
+// the poll interval and doubles with every consecutive failure. A cap below
+// the poll interval disables the backoff.
+func MaxRetryDelay(delay time.Duration) FollowerOption {
+	return func(c *followerConfig) { c.maxRetryDelay = delay }
+}
+
+// OnSyncError is called with every error Run retries.
+func OnSyncError(fn func(error)) FollowerOption {
+	return func(c *followerConfig) { c.onSyncError = fn }
+}
+
+// MaxReorgDepth bounds how many blocks the follower remembers, and so how
+// deep a reorg it can unwind.
+func MaxReorgDepth(depth int) FollowerOption {
+	return func(c *followerConfig) { c.maxReorgDepth = depth }
+}
+
+// FromGenesis starts the follower before the genesis block, ignoring the
+// checkpoint, so that the genesis block is the first one connected.
+func FromGenesis() FollowerOption {
+	return func(c *followerConfig) { c.fromGenesis = true }
+}
+
+func EventBuffer(size int) FollowerOption {
+	return func(c *followerConfig) { c.eventBuffer = size }
+}
+
+// ChainFollower polls the chain tip and emits BlockConnected and
+// BlockDisconnected events in order, checking PreviousBlockHash linkage of
+// every new block so that reorgs are reported instead of skipped.
+type ChainFollower struct {
+	api    BlockAPI
+	config followerConfig
+	events chan ChainEvent
+
+	mu    sync.Mutex
+	chain []*Block
+}
+
+// NewChainFollower resumes from checkpoint: the first connected block is the
+// one at checkpoint.Height+1, so the zero Checkpoint starts after the genesis
+// block; use FromGenesis to include it. An empty checkpoint.Hash is resolved
+// to the current best block at checkpoint.Height on the first sync.
+func NewChainFollower(api BlockAPI, checkpoint Checkpoint, opts ...FollowerOption) *ChainFollower {
+	config := followerConfig{pollInterval: 30 * time.Second, maxRetryDelay: 5 * time.Minute, maxReorgDepth: 100, eventBuffer: 16}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.fromGenesis {
+		checkpoint = Checkpoint{Height: beforeGenesis}
+	}
+	return &ChainFollower{
+		api:    api,
+		config: config,
+		events: make(chan ChainEvent, config.eventBuffer),
+		chain:  []*Block{{ID: checkpoint.Hash, Height: checkpoint.Height}},
+	}
+}
+
+// Events is closed when Run returns.
+func (f *ChainFollower) Events() <-chan ChainEvent {
+	return f.events
+}
+
+func (f *ChainFollower) Checkpoint() Checkpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkpoint()
+}
+
+// Run syncs every poll interval until ctx is done or the follower cannot
+// continue, which is only the case after ErrReorgTooDeep. Other failed syncs
+// are retried with backoff. After an error a new follower can be resumed from
+// Checkpoint.
+func (f *ChainFollower) Run(ctx context.Context) error {
+	defer close(f.events)
+	failures := 0
+	for {
+		delay := f.config.pollInterval
+		if err := f.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("request canceled: %w", ctx.Err())
+			}
+			if errors.Is(err, ErrReorgTooDeep) {
+				return err
+			}
+			if f.config.onSyncError != nil {
+				f.config.onSyncError(err)
+			}
+			ceiling := f.config.maxRetryDelay
+			if ceiling < delay {
+				ceiling = delay
+			}
+			for i := 0; i < failures && delay < ceiling; i++ {
+				delay *= 2
+			}
+			if delay > ceiling {
+				delay = ceiling
+			}
+			failures++
+		} else {
+			failures = 0
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("request canceled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Sync unwinds any reorg and connects every block up to the current tip.
+func (f *ChainFollower) Sync(ctx context.Context) error {
+	if err := f.resolveCheckpoint(ctx); err != nil {
+		return err
+	}
+
+	for relinking := false; ; relinking = true {
+		disconnected, err := f.unwind(ctx)
+		if err != nil {
+			return err
+		}
+		if relinking && disconnected == 0 {
+			top := f.top()
+			return fmt.Errorf("%w: block %d does not link to %s", ErrInconsistentChain, top.Height+1, top.ID)
+		}
+
+		tipHeight, err := f.api.GetLastBlockHeightCtx(ctx)
+		if err != nil {
+			return err
+		}
+
+		linked := true
+		for linked && f.top().Height < tipHeight {
+			if linked, err = f.connectNext(ctx); err != nil {
+				return err
+			}
+		}
+		if linked {
+			return nil
+		}
+		// The chain changed under us: unwind again.
+	}
+}
+
+func (f *ChainFollower) resolveCheckpoint(ctx context.Context) error {
+	top := f.top()
+	if top.ID != "" || top.Height == beforeGenesis {
+		return nil
+	}
+	hash, err := f.api.GetBlockHashCtx(ctx, top.Height)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	top.ID = hash
+	f.mu.Unlock()
+	return nil
+}
+
+// unwind disconnects remembered blocks until the top one is in the best
+// chain again and returns how many it disconnected. Below the remembered
+// window, e.g. right after resuming from a checkpoint, it walks back through
+// PreviousBlockHash.
+func (f *ChainFollower) unwind(ctx context.Context) (int, error) {
+	for depth := 0; ; depth++ {
+		top := f.top()
+		if top.Height == beforeGenesis {
+			return depth, nil
+		}
+		hash, err := f.api.GetBlockHashCtx(ctx, top.Height)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return depth, err
+		}
+		if err == nil && hash == top.ID {
+			return depth, nil
+		}
+		if depth >= f.config.maxReorgDepth {
+			return depth, fmt.Errorf("%w: fork below %d/%s", ErrReorgTooDeep, top.Height, top.ID)
+		}
+
+		if top.PreviousBlockHash == "" {
+			block, err := f.api.GetBlockCtx(ctx, top.ID)
+			if err != nil {
+				return depth, err
+			}
+			top = block
+		}
+
+		f.mu.Lock()
+		f.chain = f.chain[:len(f.chain)-1]
+		if len(f.chain) == 0 {
+			f.chain = append(f.chain, &Block{ID: top.PreviousBlockHash, Height: top.Height - 1})
+		}
+		checkpoint := f.checkpoint()
+		f.mu.Unlock()
+		if err := f.emit(ctx, ChainEvent{Type: BlockDisconnected, Block: top, Checkpoint: checkpoint}); err != nil {
+			return depth, err
+		}
+	}
+}
+
+// connectNext connects the block following the top one, or reports false
+// when it does not link to it.
+func (f *ChainFollower) connectNext(ctx context.Context) (bool, error) {
+	top := f.top()
+	hash, err := f.api.GetBlockHashCtx(ctx, top.Height+1)
+	if err != nil {
+		return false, err
+	}
+	block, err := f.api.GetBlockCtx(ctx, hash)
+	if err != nil {
+		return false, err
+	}
+	if block.PreviousBlockHash != top.ID || block.Height != top.Height+1 {
+		return false, nil
+	}
+
+	f.mu.Lock()
+	f.chain = append(f.chain, block)
+	if len(f.chain) > f.config.maxReorgDepth+1 {
+		f.chain = f.chain[len(f.chain)-f.config.maxReorgDepth-1:]
+	}
+	checkpoint := f.checkpoint()
+	f.mu.Unlock()
+	return true, f.emit(ctx, ChainEvent{Type: BlockConnected, Block: block, Checkpoint: checkpoint})
+}
+
+func (f *ChainFollower) emit(ctx context.Context, event ChainEvent) error {
+	select {
+	case f.events <- event:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("request canceled: %w", ctx.Err())
+	}
+}
+
+func (f *ChainFollower) top() *Block {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.chain[len(f.chain)-1]
+}
+
+func (f *ChainFollower) checkpoint() Checkpoint {
+	top := f.chain[len(f.chain)-1]
+	return Checkpoint{Height: top.Height, Hash: top.ID}
+}
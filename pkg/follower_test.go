@@ -0,0 +1,218 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"testing"
+	"time"
+)
+
+func blockHash(branch string, height int) pkg.BlockHash {
+	return pkg.BlockHash(fmt.Sprintf("%s-%d", branch, height))
+}
+
+// extend adds blocks (from, to] of branch on top of parent.
+func extend(fake *esploratest.Fake, parent pkg.BlockHash, branch string, from, to int) {
+	for height := from + 1; height <= to; height++ {
+		fake.AddBlock(&pkg.Block{ID: blockHash(branch, height), Height: pkg.BlockHeight(height), PreviousBlockHash: parent})
+		parent = blockHash(branch, height)
+	}
+}
+
+func drain(follower *pkg.ChainFollower) []pkg.ChainEvent {
+	events := make([]pkg.ChainEvent, 0)
+	for {
+		select {
+		case event := <-follower.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func describe(events []pkg.ChainEvent) []string {
+	described := make([]string, 0, len(events))
+	for _, event := range events {
+		described = append(described, fmt.Sprintf("%s %s", event.Type, event.Block.ID))
+	}
+	return described
+}
+
+func assertEvents(t *testing.T, events []pkg.ChainEvent, expected ...string) {
+	t.Helper()
+	got := describe(events)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+}
+
+func TestChainFollower_Reorg(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 3)
+
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: 1, Hash: blockHash("a", 1)}, pkg.EventBuffer(64))
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEvents(t, drain(follower), "BlockConnected a-2", "BlockConnected a-3")
+
+	extend(fake, blockHash("a", 1), "b", 1, 4)
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEvents(t, drain(follower),
+		"BlockDisconnected a-3", "BlockDisconnected a-2",
+		"BlockConnected b-2", "BlockConnected b-3", "BlockConnected b-4")
+
+	if checkpoint := follower.Checkpoint(); checkpoint.Height != 4 || checkpoint.Hash != blockHash("b", 4) {
+		t.Errorf("invalid checkpoint %+v", checkpoint)
+	}
+}
+
+func TestChainFollower_ResumeAcrossReorg(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 3)
+	stale := pkg.Checkpoint{Height: 3, Hash: blockHash("a", 3)}
+	extend(fake, blockHash("a", 1), "b", 1, 3)
+
+	follower := pkg.NewChainFollower(fake, stale, pkg.EventBuffer(64))
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEvents(t, drain(follower),
+		"BlockDisconnected a-3", "BlockDisconnected a-2",
+		"BlockConnected b-2", "BlockConnected b-3")
+}
+
+func TestChainFollower_ReorgTooDeep(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 5)
+
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: 5, Hash: blockHash("a", 5)}, pkg.MaxReorgDepth(2), pkg.EventBuffer(64))
+	extend(fake, blockHash("a", 1), "b", 1, 6)
+
+	if err := follower.Sync(context.Background()); !errors.Is(err, pkg.ErrReorgTooDeep) {
+		t.Fatalf("expected ErrReorgTooDeep, got %v", err)
+	}
+}
+
+func TestChainFollower_Run(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{}, pkg.PollInterval(5*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- follower.Run(ctx) }()
+
+	extend(fake, blockHash("a", 0), "a", 0, 2)
+	for _, expected := range []pkg.BlockHash{blockHash("a", 1), blockHash("a", 2)} {
+		select {
+		case event := <-follower.Events():
+			if event.Type != pkg.BlockConnected || event.Block.ID != expected {
+				t.Fatalf("unexpected event %s %s", event.Type, event.Block.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-follower.Events(); ok {
+		t.Errorf("events channel not closed")
+	}
+}
+
+func TestChainFollower_RunRetries(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 3)
+	fake.SetError(pkg.ErrUnavailable)
+
+	syncErrors := make(chan error, 16)
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: 3, Hash: blockHash("a", 3)},
+		pkg.PollInterval(time.Millisecond), pkg.MaxRetryDelay(4*time.Millisecond), pkg.MaxReorgDepth(1),
+		pkg.OnSyncError(func(err error) {
+			select {
+			case syncErrors <- err:
+			default:
+			}
+		}))
+	done := make(chan error)
+	go func() { done <- follower.Run(context.Background()) }()
+
+	select {
+	case err := <-syncErrors:
+		if !errors.Is(err, pkg.ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a sync error")
+	}
+	fake.SetError(nil)
+	extend(fake, blockHash("a", 3), "a", 3, 4)
+	select {
+	case event := <-follower.Events():
+		if event.Type != pkg.BlockConnected || event.Block.ID != blockHash("a", 4) {
+			t.Fatalf("unexpected event %s %s", event.Type, event.Block.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	extend(fake, blockHash("a", 1), "b", 1, 5)
+	select {
+	case err := <-done:
+		if !errors.Is(err, pkg.ErrReorgTooDeep) {
+			t.Errorf("expected ErrReorgTooDeep, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after a deep reorg")
+	}
+}
+
+func TestChainFollower_FromGenesis(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 2)
+
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: 5, Hash: "ignored"}, pkg.FromGenesis(), pkg.EventBuffer(64))
+	if checkpoint := follower.Checkpoint(); checkpoint.Height != -1 || checkpoint.Hash != "" {
+		t.Errorf("invalid checkpoint %+v", checkpoint)
+	}
+	if err := follower.Sync(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEvents(t, drain(follower), "BlockConnected a-0", "BlockConnected a-1", "BlockConnected a-2")
+
+	// A checkpoint persisted before the first block resumes at genesis too.
+	resumed := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: -1}, pkg.EventBuffer(64))
+	if err := resumed.Sync(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertEvents(t, drain(resumed), "BlockConnected a-0", "BlockConnected a-1", "BlockConnected a-2")
+}
+
+func TestChainFollower_InconsistentChain(t *testing.T) {
+	fake := esploratest.New()
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", 0), Height: 0})
+	extend(fake, blockHash("a", 0), "a", 0, 1)
+	// The block at height 2 claims a parent the server does not have.
+	fake.AddBlock(&pkg.Block{ID: blockHash("b", 2), Height: 2, PreviousBlockHash: blockHash("b", 1)})
+
+	follower := pkg.NewChainFollower(fake, pkg.Checkpoint{Height: 1, Hash: blockHash("a", 1)}, pkg.EventBuffer(64))
+	if err := follower.Sync(context.Background()); !errors.Is(err, pkg.ErrInconsistentChain) {
+		t.Fatalf("expected ErrInconsistentChain, got %v", err)
+	}
+	assertEvents(t, drain(follower))
+}
@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const blockHeaderLen = 80
+
+var (
+	ErrInvalidProofOfWork = errors.New("invalid proof of work")
+	ErrHeaderHashMismatch = errors.New("header does not hash to the requested block")
+)
+
+// BlockHeader is a decoded block header. Hashes are in the displayed,
+// byte-reversed order used by Block.
+type BlockHeader struct {
+	Version           int32
+	PreviousBlockHash BlockHash
+	MerkleRoot        string
+	Timestamp         uint32
+	Bits              uint32
+	Nonce             uint32
+	// Raw is the serialized header the fields were decoded from; Hash and
+	// CheckProofOfWork work on it.
+	Raw []byte
+}
+
+// Decode parses a serialized header as returned by GetBlockHeader or the
+// Electrum blockchain.block.header(s) methods.
+func (h BlockHeaderHex) Decode() (*BlockHeader, error) {
+	raw, err := hex.DecodeString(string(h))
+	if err != nil {
+		return nil, &DecodeError{Path: "block header", Body: string(h), Err: err}
+	}
+	return DecodeBlockHeader(raw)
+}
+
+func DecodeBlockHeader(raw []byte) (*BlockHeader, error) {
+	if len(raw) != blockHeaderLen {
+		return nil, &DecodeError{Path: "block header", Body: hex.EncodeToString(raw), Err: fmt.Errorf("expected %d bytes, got %d", blockHeaderLen, len(raw))}
+	}
+	return &BlockHeader{
+		Version:           int32(binary.LittleEndian.Uint32(raw[0:4])),
+		PreviousBlockHash: BlockHash(hex.EncodeToString(reverseBytes(raw[4:36]))),
+		MerkleRoot:        hex.EncodeToString(reverseBytes(raw[36:68])),
+		Timestamp:         binary.LittleEndian.Uint32(raw[68:72]),
+		Bits:              binary.LittleEndian.Uint32(raw[72:76]),
+		Nonce:             binary.LittleEndian.Uint32(raw[76:80]),
+		Raw:               append([]byte(nil), raw...),
+	}, nil
+}
+
+// NewBlockHeader serializes the header fields of block. The genesis block
+// may leave PreviousBlockHash empty.
+func NewBlockHeader(block *Block) (*BlockHeader, error) {
+	raw := make([]byte, blockHeaderLen)
+	binary.LittleEndian.PutUint32(raw[0:4], uint32(block.Version))
+	if block.PreviousBlockHash != "" {
+		previous, err := decodeHash(string(block.PreviousBlockHash))
+		if err != nil {
+			return nil, fmt.Errorf("previousblockhash: %w", err)
+		}
+		copy(raw[4:36], previous)
+	}
+	merkleRoot, err := decodeHash(block.MerkleRoot)
+	if err != nil {
+		return nil, fmt.Errorf("merkle_root: %w", err)
+	}
+	copy(raw[36:68], merkleRoot)
+	binary.LittleEndian.PutUint32(raw[68:72], uint32(block.Timestamp))
+	binary.LittleEndian.PutUint32(raw[72:76], uint32(block.Bits))
+	binary.LittleEndian.PutUint32(raw[76:80], uint32(block.Nonce))
+	return DecodeBlockHeader(raw)
+}
+
+func (h *BlockHeader) Hex() BlockHeaderHex {
+	return BlockHeaderHex(hex.EncodeToString(h.Raw))
+}
+
+// Hash returns the block hash: the double SHA256 of the header, reversed.
+func (h *BlockHeader) Hash() BlockHash {
+	return BlockHash(hex.EncodeToString(reverseBytes(doubleSHA256(h.Raw))))
+}
+
+// Target expands the compact Bits encoding.
+func (h *BlockHeader) Target() (*big.Int, error) {
+	return CompactToTarget(h.Bits)
+}
+
+// CheckProofOfWork verifies that the header hash does not exceed the target
+// encoded in Bits. It does not check that Bits itself is what the
+// difficulty adjustment requires at that height.
+func (h *BlockHeader) CheckProofOfWork() error {
+	target, err := h.Target()
+	if err != nil {
+		return err
+	}
+	hash := new(big.Int).SetBytes(reverseBytes(doubleSHA256(h.Raw)))
+	if hash.Cmp(target) > 0 {
+		return fmt.Errorf("%w: hash %s above target %064x", ErrInvalidProofOfWork, h.Hash(), target)
+	}
+	return nil
+}
+
+// CompactToTarget expands the nBits encoding: a base-256 exponent in the
+// top byte and a 23-bit mantissa with a sign bit. Negative, zero and
+// overflowing targets are rejected, as bitcoind does.
+func CompactToTarget(bits uint32) (*big.Int, error) {
+	exponent := uint(bits >> 24)
+	mantissa := int64(bits & 0x007fffff)
+	if mantissa == 0 {
+		return nil, fmt.Errorf("%w: zero target 0x%08x", ErrInvalidProofOfWork, bits)
+	}
+	if bits&0x00800000 != 0 {
+		return nil, fmt.Errorf("%w: negative target 0x%08x", ErrInvalidProofOfWork, bits)
+	}
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+	if target.Sign() == 0 || target.BitLen() > 256 {
+		return nil, fmt.Errorf("%w: target out of range 0x%08x", ErrInvalidProofOfWork, bits)
+	}
+	return target, nil
+}
+
+// TargetToCompact is the inverse of CompactToTarget, rounding the target
+// down to the 23-bit mantissa.
+func TargetToCompact(target *big.Int) uint32 {
+	size := uint((target.BitLen() + 7) / 8)
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(target.Uint64() << (8 * (3 - size)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(size-3)).Uint64())
+	}
+	// Keep the sign bit clear by moving to a larger exponent.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		size++
+	}
+	return uint32(size)<<24 | mantissa
+}
+
+func (c *HTTPClient) GetVerifiedBlockHeader(hash BlockHash) (*BlockHeader, error) {
+	return GetVerifiedBlockHeader(context.Background(), c, hash)
+}
+
+func (c *HTTPClient) GetVerifiedBlockHeaderCtx(ctx context.Context, hash BlockHash) (*BlockHeader, error) {
+	return GetVerifiedBlockHeader(ctx, c, hash)
+}
+
+// GetVerifiedBlockHeader fetches the header of hash and checks that it
+// hashes to hash and satisfies its own proof of work.
+func GetVerifiedBlockHeader(ctx context.Context, api BlockAPI, hash BlockHash) (*BlockHeader, error) {
+	headerHex, err := api.GetBlockHeaderCtx(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	header, err := headerHex.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != hash {
+		return nil, fmt.Errorf("%w: requested %s, got %s", ErrHeaderHashMismatch, hash, header.Hash())
+	}
+	if err := header.CheckProofOfWork(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const genesisHeader BlockHeaderHex = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c"
+
+func TestBlockHeader_Decode(t *testing.T) {
+	header, err := genesisHeader.Decode()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if header.Version != 1 || header.Timestamp != 1231006505 || header.Bits != 0x1d00ffff || header.Nonce != 2083236893 {
+		t.Errorf("unexpected header fields %+v", header)
+	}
+	if header.PreviousBlockHash != "0000000000000000000000000000000000000000000000000000000000000000" {
+		t.Errorf("unexpected previous block hash %s", header.PreviousBlockHash)
+	}
+	if header.MerkleRoot != "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b" {
+		t.Errorf("unexpected merkle root %s", header.MerkleRoot)
+	}
+	if header.Hash() != "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f" {
+		t.Errorf("unexpected hash %s", header.Hash())
+	}
+	if err := header.CheckProofOfWork(); err != nil {
+		t.Error(err.Error())
+	}
+
+	if _, err := BlockHeaderHex("0100").Decode(); err == nil {
+		t.Errorf("expected error for truncated header")
+	}
+}
+
+func TestNewBlockHeader(t *testing.T) {
+	header, err := NewBlockHeader(&Block{
+		Version:           1,
+		PreviousBlockHash: "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+		MerkleRoot:        "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		Timestamp:         1293623863,
+		Bits:              0x1b04864c,
+		Nonce:             274148111,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if header.Hash() != "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506" {
+		t.Errorf("unexpected hash %s", header.Hash())
+	}
+	if err := header.CheckProofOfWork(); err != nil {
+		t.Error(err.Error())
+	}
+
+	header.Raw[76]++
+	if err := header.CheckProofOfWork(); !errors.Is(err, ErrInvalidProofOfWork) {
+		t.Errorf("expected ErrInvalidProofOfWork for a tampered nonce, got %v", err)
+	}
+}
+
+func TestCompactToTarget(t *testing.T) {
+	expected, _ := new(big.Int).SetString("00000000ffff0000000000000000000000000000000000000000000000000000", 16)
+	target, err := CompactToTarget(0x1d00ffff)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if target.Cmp(expected) != 0 {
+		t.Errorf("unexpected target %x", target)
+	}
+
+	target, err = CompactToTarget(0x03123456)
+	if err != nil || target.Int64() != 0x123456 {
+		t.Errorf("unexpected target %v %v", target, err)
+	}
+	target, err = CompactToTarget(0x02123456)
+	if err != nil || target.Int64() != 0x1234 {
+		t.Errorf("unexpected target %v %v", target, err)
+	}
+
+	for _, bits := range []uint32{0x1d00ffff, 0x1b04864c, 0x207fffff, 0x03123456} {
+		target, err := CompactToTarget(bits)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if compact := TargetToCompact(target); compact != bits {
+			t.Errorf("0x%08x: round trip gave 0x%08x", bits, compact)
+		}
+	}
+	if compact := TargetToCompact(big.NewInt(0x80)); compact != 0x02008000 {
+		t.Errorf("expected sign bit to be avoided, got 0x%08x", compact)
+	}
+
+	for _, bits := range []uint32{0x1d000000, 0x04923456, 0xff123456, 0x01003456} {
+		if _, err := CompactToTarget(bits); !errors.Is(err, ErrInvalidProofOfWork) {
+			t.Errorf("0x%08x: expected ErrInvalidProofOfWork, got %v", bits, err)
+		}
+	}
+}
+
+func TestGetVerifiedBlockHeader_Mismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(genesisHeader))
+	}))
+	defer server.Close()
+
+	c := NewHTTPClient(server.URL, false)
+	_, err := GetVerifiedBlockHeader(context.Background(), c, "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506")
+	if !errors.Is(err, ErrHeaderHashMismatch) {
+		t.Errorf("expected ErrHeaderHashMismatch, got %v", err)
+	}
+}
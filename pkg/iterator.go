@@ -0,0 +1,384 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrHistoryNotAdvancing is returned when the server answers a history page
+// request with the page that was just read, as a server ignoring the
+// last-seen txid would.
+var ErrHistoryNotAdvancing = errors.New("history paging did not advance")
+
+// ErrIncompleteBlock is returned when a block transaction page is shorter or
+// longer than the block's tx_count implies.
+var ErrIncompleteBlock = errors.New("incomplete block transaction page")
+
+// electrs returns confirmed history in pages of this many transactions.
+const chainTxsPerPage = 25
+
+type HistoryOption func(*historyConfig)
+
+type historyConfig struct {
+	includeMempool bool
+	stop           func(tx *Transaction) bool
+}
+
+// IncludeMempool yields the unconfirmed transactions before the confirmed
+// history. electrs returns at most 50 of them, newest first, and gives no
+// sign when there are more, so a busy address may be missing older
+// unconfirmed transactions.
+func IncludeMempool() HistoryOption {
+	return func(c *historyConfig) { c.includeMempool = true }
+}
+
+// StopWhen ends the iteration at the first transaction for which stop
+// returns true; that transaction is not yielded.
+func StopWhen(stop func(tx *Transaction) bool) HistoryOption {
+	return func(c *historyConfig) { c.stop = stop }
+}
+
+// StopAtHeight ends the iteration at the first confirmed transaction below
+// height, e.g. the last height a reconciliation job already processed.
+func StopAtHeight(height BlockHeight) HistoryOption {
+	return StopWhen(func(tx *Transaction) bool {
+		return tx.Status.Confirmed && tx.Status.BlockHeight < height
+	})
+}
+
+// StopAtTxID ends the iteration at txID, e.g. the newest transaction a job
+// has already seen.
+func StopAtTxID(txID TxID) HistoryOption {
+	return StopWhen(func(tx *Transaction) bool {
+		return tx.ID == txID
+	})
+}
+
+// TxIterator walks a transaction history newest first, fetching pages on
+// demand:
+//
+//	it := client.AddressHistory(address, IncludeMempool())
+//	for it.Next() {
+//		tx := it.Tx()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type TxIterator struct {
+	ctx     context.Context
+	mempool func(ctx context.Context) ([]*Transaction, error)
+	chain   func(ctx context.Context, lastTxID TxID) ([]*Transaction, error)
+	config  historyConfig
+
+	page      []*Transaction
+	tx        *Transaction
+	lastTxID  TxID
+	inMempool bool
+	exhausted bool
+	err       error
+}
+
+func (c *HTTPClient) AddressHistory(address Address, opts ...HistoryOption) *TxIterator {
+	return NewAddressHistory(context.Background(), c, address, opts...)
+}
+
+func (c *HTTPClient) AddressHistoryCtx(ctx context.Context, address Address, opts ...HistoryOption) *TxIterator {
+	return NewAddressHistory(ctx, c, address, opts...)
+}
+
+func (c *HTTPClient) ScriptHashHistory(hash ScriptHash, opts ...HistoryOption) *TxIterator {
+	return NewScriptHashHistory(context.Background(), c, hash, opts...)
+}
+
+func (c *HTTPClient) ScriptHashHistoryCtx(ctx context.Context, hash ScriptHash, opts ...HistoryOption) *TxIterator {
+	return NewScriptHashHistory(ctx, c, hash, opts...)
+}
+
+// NewAddressHistory iterates the history of address through any AddressAPI,
+// e.g. an esploratest.Fake.
+func NewAddressHistory(ctx context.Context, api AddressAPI, address Address, opts ...HistoryOption) *TxIterator {
+	return newTxIterator(ctx,
+		func(ctx context.Context) ([]*Transaction, error) {
+			return api.GetAddressTransactionsInMemPoolCtx(ctx, address)
+		},
+		func(ctx context.Context, lastTxID TxID) ([]*Transaction, error) {
+			return api.GetAddressTransactionsLatestCtx(ctx, address, lastTxID)
+		},
+		opts)
+}
+
+func NewScriptHashHistory(ctx context.Context, api AddressAPI, hash ScriptHash, opts ...HistoryOption) *TxIterator {
+	return newTxIterator(ctx,
+		func(ctx context.Context) ([]*Transaction, error) {
+			return api.GetScriptHashTransactionsInMemPoolCtx(ctx, hash)
+		},
+		func(ctx context.Context, lastTxID TxID) ([]*Transaction, error) {
+			return api.GetScriptHashTransactionsLatestCtx(ctx, Address(hash), lastTxID)
+		},
+		opts)
+}
+
+func newTxIterator(ctx context.Context,
+	mempool func(ctx context.Context) ([]*Transaction, error),
+	chain func(ctx context.Context, lastTxID TxID) ([]*Transaction, error),
+	opts []HistoryOption) *TxIterator {
+	it := &TxIterator{ctx: ctx, mempool: mempool, chain: chain}
+	for _, opt := range opts {
+		opt(&it.config)
+	}
+	it.inMempool = it.config.includeMempool
+	return it
+}
+
+// Next advances to the next transaction and reports whether there is one.
+// It returns false at the end of the history, when the stop condition is
+// met, or on error.
+func (it *TxIterator) Next() bool {
+	it.tx = nil
+	if it.err != nil {
+		return false
+	}
+
+	for len(it.page) == 0 {
+		if it.exhausted {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = fmt.Errorf("request canceled: %w", err)
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	tx := it.page[0]
+	it.page = it.page[1:]
+	if it.config.stop != nil && it.config.stop(tx) {
+		it.page, it.exhausted = nil, true
+		return false
+	}
+	it.tx = tx
+	return true
+}
+
+func (it *TxIterator) fetch() error {
+	if it.inMempool {
+		it.inMempool = false
+		page, err := it.mempool(it.ctx)
+		if err != nil {
+			return err
+		}
+		it.page = page
+		return nil
+	}
+
+	page, err := it.chain(it.ctx, it.lastTxID)
+	if err != nil {
+		return err
+	}
+	if len(page) < chainTxsPerPage {
+		it.exhausted = true
+	}
+	if len(page) > 0 {
+		last := page[len(page)-1].ID
+		if last == it.lastTxID {
+			return fmt.Errorf("%w: page after %s ends with %s again", ErrHistoryNotAdvancing, it.lastTxID, last)
+		}
+		it.lastTxID = last
+	}
+	it.page = page
+	return nil
+}
+
+// Tx returns the transaction Next advanced to.
+func (it *TxIterator) Tx() *Transaction {
+	return it.tx
+}
+
+func (it *TxIterator) Err() error {
+	return it.err
+}
+
+// All drains the iterator.
+func (it *TxIterator) All() ([]*Transaction, error) {
+	transactions := make([]*Transaction, 0)
+	for it.Next() {
+		transactions = append(transactions, it.Tx())
+	}
+	return transactions, it.Err()
+}
+
+// electrs returns block transactions in pages of this many transactions.
+const blockTxsPerPage = 25
+
+// IndexedTransaction is a transaction together with its position in the
+// block.
+type IndexedTransaction struct {
+	Index int32
+	*Transaction
+}
+
+type BlockTxOption func(*blockTxConfig)
+
+type blockTxConfig struct {
+	workers int
+}
+
+// FetchConcurrency fetches up to workers pages at once. Transactions are
+// still yielded in block order.
+func FetchConcurrency(workers int) BlockTxOption {
+	return func(c *blockTxConfig) { c.workers = workers }
+}
+
+// BlockTxIterator walks all transactions of a block in order, paging through
+// /block/:hash/txs/:start.
+type BlockTxIterator struct {
+	ctx    context.Context
+	api    BlockAPI
+	hash   BlockHash
+	config blockTxConfig
+
+	txCount int32
+	next    int32
+	page    []IndexedTransaction
+	tx      IndexedTransaction
+	started bool
+	err     error
+}
+
+func (c *HTTPClient) BlockTransactions(hash BlockHash, opts ...BlockTxOption) *BlockTxIterator {
+	return NewBlockTransactions(context.Background(), c, hash, opts...)
+}
+
+func (c *HTTPClient) BlockTransactionsCtx(ctx context.Context, hash BlockHash, opts ...BlockTxOption) *BlockTxIterator {
+	return NewBlockTransactions(ctx, c, hash, opts...)
+}
+
+func (c *HTTPClient) GetAllBlockTransactions(hash BlockHash, opts ...BlockTxOption) ([]IndexedTransaction, error) {
+	return c.BlockTransactions(hash, opts...).All()
+}
+
+func (c *HTTPClient) GetAllBlockTransactionsCtx(ctx context.Context, hash BlockHash, opts ...BlockTxOption) ([]IndexedTransaction, error) {
+	return c.BlockTransactionsCtx(ctx, hash, opts...).All()
+}
+
+// NewBlockTransactions iterates the transactions of a block through any
+// BlockAPI.
+func NewBlockTransactions(ctx context.Context, api BlockAPI, hash BlockHash, opts ...BlockTxOption) *BlockTxIterator {
+	it := &BlockTxIterator{ctx: ctx, api: api, hash: hash, config: blockTxConfig{workers: 1}}
+	for _, opt := range opts {
+		opt(&it.config)
+	}
+	if it.config.workers < 1 {
+		it.config.workers = 1
+	}
+	return it
+}
+
+func (it *BlockTxIterator) Next() bool {
+	it.tx = IndexedTransaction{}
+	if it.err != nil {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		block, err := it.api.GetBlockCtx(it.ctx, it.hash)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.txCount = block.TxCount
+	}
+
+	if len(it.page) == 0 {
+		if it.next >= it.txCount {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = fmt.Errorf("request canceled: %w", err)
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	it.tx = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// fetch loads the next batch of up to config.workers pages concurrently.
+// The first failure cancels the other fetches of the batch.
+func (it *BlockTxIterator) fetch() error {
+	starts := make([]int32, 0, it.config.workers)
+	for start := it.next; start < it.txCount && len(starts) < it.config.workers; start += blockTxsPerPage {
+		starts = append(starts, start)
+	}
+
+	ctx, cancel := context.WithCancel(it.ctx)
+	defer cancel()
+	pages := make([][]*Transaction, len(starts))
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for i, start := range starts {
+		wg.Add(1)
+		go func(i int, start int32) {
+			defer wg.Done()
+			page, err := it.api.GetBlockTransactionsCtx(ctx, it.hash, start)
+			if err == nil {
+				expected := it.txCount - start
+				if expected > blockTxsPerPage {
+					expected = blockTxsPerPage
+				}
+				if int32(len(page)) != expected {
+					err = fmt.Errorf("%w: block %s at %d has %d transactions, expected %d", ErrIncompleteBlock, it.hash, start, len(page), expected)
+				}
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			pages[i] = page
+		}(i, start)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	for i, start := range starts {
+		for j, tx := range pages[i] {
+			it.page = append(it.page, IndexedTransaction{Index: start + int32(j), Transaction: tx})
+		}
+		it.next = start + blockTxsPerPage
+	}
+	return nil
+}
+
+func (it *BlockTxIterator) Tx() IndexedTransaction {
+	return it.tx
+}
+
+func (it *BlockTxIterator) Err() error {
+	return it.err
+}
+
+func (it *BlockTxIterator) All() ([]IndexedTransaction, error) {
+	transactions := make([]IndexedTransaction, 0, it.txCount)
+	for it.Next() {
+		transactions = append(transactions, it.Tx())
+	}
+	return transactions, it.Err()
+}
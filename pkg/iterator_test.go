@@ -0,0 +1,246 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHistoryServer serves 2 mempool and 60 confirmed transactions for any
+// address or scripthash, paging like electrs. Confirmed tx i is at height
+// 1000-i and has txid "c<i>".
+func newHistoryServer(requests *int32) *httptest.Server {
+	confirmed := make([]*Transaction, 60)
+	for i := range confirmed {
+		confirmed[i] = &Transaction{ID: TxID(fmt.Sprintf("c%d", i)), Status: TransactionStatus{Confirmed: true, BlockHeight: BlockHeight(1000 - i)}}
+	}
+	mempool := []*Transaction{{ID: "m0"}, {ID: "m1"}}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		var page []*Transaction
+		switch {
+		case len(parts) == 4 && parts[3] == "mempool":
+			page = mempool
+		case len(parts) >= 4 && parts[3] == "chain":
+			start := 0
+			if len(parts) == 5 {
+				for i, tx := range confirmed {
+					if string(tx.ID) == parts[4] {
+						start = i + 1
+					}
+				}
+			}
+			end := start + chainTxsPerPage
+			if end > len(confirmed) {
+				end = len(confirmed)
+			}
+			page = confirmed[start:end]
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func TestTxIterator_AddressHistory(t *testing.T) {
+	var requests int32
+	server := newHistoryServer(&requests)
+	defer server.Close()
+
+	transactions, err := NewHTTPClient(server.URL, false).AddressHistory("addr", IncludeMempool()).All()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 62 || transactions[0].ID != "m0" || transactions[2].ID != "c0" || transactions[61].ID != "c59" {
+		t.Errorf("invalid history of %d transactions", len(transactions))
+	}
+	if requests != 4 {
+		t.Errorf("expected 4 requests, got %d", requests)
+	}
+}
+
+func TestTxIterator_StopAtHeight(t *testing.T) {
+	var requests int32
+	server := newHistoryServer(&requests)
+	defer server.Close()
+
+	transactions, err := NewHTTPClient(server.URL, false).ScriptHashHistory("hash", StopAtHeight(990)).All()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 11 || transactions[10].Status.BlockHeight != 990 {
+		t.Errorf("invalid history of %d transactions", len(transactions))
+	}
+	if requests != 1 {
+		t.Errorf("expected a single request, got %d", requests)
+	}
+}
+
+func TestTxIterator_StopAtTxID(t *testing.T) {
+	var requests int32
+	server := newHistoryServer(&requests)
+	defer server.Close()
+
+	transactions, err := NewHTTPClient(server.URL, false).AddressHistory("addr", StopAtTxID("c30")).All()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(transactions) != 30 || transactions[29].ID != "c29" {
+		t.Errorf("invalid history of %d transactions", len(transactions))
+	}
+}
+
+func TestTxIterator_NotAdvancing(t *testing.T) {
+	page := make([]*Transaction, chainTxsPerPage)
+	for i := range page {
+		page[i] = &Transaction{ID: TxID(fmt.Sprintf("c%d", i)), Status: TransactionStatus{Confirmed: true}}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	transactions, err := NewHTTPClient(server.URL, false).AddressHistory("addr").All()
+	if !errors.Is(err, ErrHistoryNotAdvancing) {
+		t.Fatalf("expected ErrHistoryNotAdvancing, got %v", err)
+	}
+	if len(transactions) != chainTxsPerPage {
+		t.Errorf("expected the first page only, got %d transactions", len(transactions))
+	}
+}
+
+func TestTxIterator_Canceled(t *testing.T) {
+	var requests int32
+	server := newHistoryServer(&requests)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewHTTPClient(server.URL, false).AddressHistoryCtx(ctx, "addr")
+	for i := 0; i < chainTxsPerPage; i++ {
+		if !it.Next() {
+			t.Fatalf("unexpected end of history: %v", it.Err())
+		}
+	}
+	cancel()
+
+	if it.Next() {
+		t.Fatal("expected iteration to stop")
+	}
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", it.Err())
+	}
+}
+
+// newBlockServer serves a block of txCount transactions with txid "t<i>".
+func newBlockServer(txCount int, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 2:
+			_ = json.NewEncoder(w).Encode(&Block{ID: BlockHash(parts[1]), TxCount: int32(txCount)})
+		case len(parts) == 4 && parts[2] == "txs":
+			var start int
+			_, _ = fmt.Sscanf(parts[3], "%d", &start)
+			page := make([]*Transaction, 0)
+			for i := start; i < txCount && i < start+blockTxsPerPage; i++ {
+				page = append(page, &Transaction{ID: TxID(fmt.Sprintf("t%d", i))})
+			}
+			_ = json.NewEncoder(w).Encode(page)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestBlockTxIterator(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		var requests int32
+		server := newBlockServer(110, &requests)
+
+		transactions, err := NewHTTPClient(server.URL, false).GetAllBlockTransactions("hash", FetchConcurrency(workers))
+		server.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(transactions) != 110 {
+			t.Fatalf("workers %d: expected 110 transactions, got %d", workers, len(transactions))
+		}
+		for i, tx := range transactions {
+			if tx.Index != int32(i) || tx.ID != TxID(fmt.Sprintf("t%d", i)) {
+				t.Fatalf("workers %d: transaction %d out of order: %d %s", workers, i, tx.Index, tx.ID)
+			}
+		}
+		if requests != 6 {
+			t.Errorf("workers %d: expected 6 requests, got %d", workers, requests)
+		}
+	}
+}
+
+func TestBlockTxIterator_Error(t *testing.T) {
+	_, err := fixtureClient.GetAllBlockTransactions(unavailableHash)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestBlockTxIterator_Incomplete(t *testing.T) {
+	// The block claims 110 transactions, but the server only has 60 of them,
+	// so the page at 50 comes back short and the one at 75 empty.
+	var requests int32
+	pages := newBlockServer(60, &requests)
+	defer pages.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block/hash" {
+			_ = json.NewEncoder(w).Encode(&Block{ID: "hash", TxCount: 110})
+			return
+		}
+		pages.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	for _, workers := range []int{1, 3} {
+		_, err := NewHTTPClient(server.URL, false).GetAllBlockTransactions("hash", FetchConcurrency(workers))
+		if !errors.Is(err, ErrIncompleteBlock) {
+			t.Errorf("workers %d: expected ErrIncompleteBlock, got %v", workers, err)
+		}
+	}
+}
+
+func TestBlockTxIterator_CancelsSiblings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/block/hash":
+			_ = json.NewEncoder(w).Encode(&Block{ID: "hash", TxCount: 50})
+		case "/block/hash/txs/0":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	started := time.Now()
+	_, err := NewHTTPClient(server.URL, false).GetAllBlockTransactions("hash", FetchConcurrency(2))
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("sibling fetch was not canceled, took %v", elapsed)
+	}
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidMerkleProof = errors.New("invalid merkle proof")
+	ErrTxUnconfirmed      = errors.New("transaction is unconfirmed")
+)
+
+// VerifyMerkleProof checks that proof links txID to merkleRoot. Both are in
+// the displayed, byte-reversed order electrs uses, as are the proof hashes.
+//
+// Where a level has an odd number of nodes the last one is paired with
+// itself, so a sibling equal to the node is only accepted when the node is
+// on the left; on the right it would be the duplicate of a phantom
+// transaction (CVE-2012-2459).
+func VerifyMerkleProof(txID TxID, proof *TransactionMerkleProof, merkleRoot string) error {
+	if proof.Pos < 0 || len(proof.Merkle) < 31 && proof.Pos >= 1<<uint(len(proof.Merkle)) {
+		return fmt.Errorf("%w: position %d out of range for %d levels", ErrInvalidMerkleProof, proof.Pos, len(proof.Merkle))
+	}
+
+	node, err := decodeHash(string(txID))
+	if err != nil {
+		return fmt.Errorf("%w: txid: %s", ErrInvalidMerkleProof, err.Error())
+	}
+	root, err := decodeHash(merkleRoot)
+	if err != nil {
+		return fmt.Errorf("%w: merkle root: %s", ErrInvalidMerkleProof, err.Error())
+	}
+
+	pos := proof.Pos
+	for level, hash := range proof.Merkle {
+		sibling, err := decodeHash(hash)
+		if err != nil {
+			return fmt.Errorf("%w: level %d: %s", ErrInvalidMerkleProof, level, err.Error())
+		}
+		if pos&1 == 1 {
+			if bytes.Equal(sibling, node) {
+				return fmt.Errorf("%w: level %d: duplicated right node", ErrInvalidMerkleProof, level)
+			}
+			node = hashPair(sibling, node)
+		} else {
+			node = hashPair(node, sibling)
+		}
+		pos >>= 1
+	}
+
+	if !bytes.Equal(node, root) {
+		return fmt.Errorf("%w: computed root %s, expected %s", ErrInvalidMerkleProof, hex.EncodeToString(reverseBytes(node)), merkleRoot)
+	}
+	return nil
+}
+
+func (c *HTTPClient) GetVerifiedTransaction(txID TxID) (*Transaction, error) {
+	return GetVerifiedTransaction(context.Background(), c, txID)
+}
+
+func (c *HTTPClient) GetVerifiedTransactionCtx(ctx context.Context, txID TxID) (*Transaction, error) {
+	return GetVerifiedTransaction(ctx, c, txID)
+}
+
+// GetVerifiedTransaction fetches a confirmed transaction together with its
+// merkle proof and block, and returns it only if the proof links it to the
+// block's merkle root. Unconfirmed transactions fail with ErrTxUnconfirmed.
+//
+// This checks the server's answers against each other; to also trust the
+// block, check its header against a header chain you validated yourself.
+func GetVerifiedTransaction(ctx context.Context, api Esplora, txID TxID) (*Transaction, error) {
+	tx, err := api.GetTransactionCtx(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ID != txID {
+		return nil, fmt.Errorf("%w: requested %s, got %s", ErrInvalidMerkleProof, txID, tx.ID)
+	}
+	if !tx.Status.Confirmed {
+		return nil, fmt.Errorf("%w: %s", ErrTxUnconfirmed, txID)
+	}
+
+	proof, err := api.GetTransactionMerkleProofCtx(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.GetBlockCtx(ctx, BlockHash(tx.Status.BlockHash))
+	if err != nil {
+		return nil, err
+	}
+	if proof.BlockHeight != block.Height {
+		return nil, fmt.Errorf("%w: proof for height %d, block %s is at %d", ErrInvalidMerkleProof, proof.BlockHeight, block.ID, block.Height)
+	}
+	if err := VerifyMerkleProof(txID, proof, block.MerkleRoot); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// decodeHash decodes a displayed 32-byte hash into internal byte order.
+func decodeHash(s string) ([]byte, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != sha256.Size {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(raw))
+	}
+	return reverseBytes(raw), nil
+}
+
+func hashPair(left, right []byte) []byte {
+	return doubleSHA256(append(append(make([]byte, 0, 64), left...), right...))
+}
+
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
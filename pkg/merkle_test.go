@@ -0,0 +1,134 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"testing"
+)
+
+// Transactions of mainnet block 100000.
+var block100000TxIDs = []pkg.TxID{
+	"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+	"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+	"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+}
+
+const block100000MerkleRoot = "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766"
+
+func TestVerifyMerkleProof(t *testing.T) {
+	cases := []struct {
+		name  string
+		txID  pkg.TxID
+		proof *pkg.TransactionMerkleProof
+		root  string
+	}{
+		{
+			name: "block 170",
+			txID: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			proof: &pkg.TransactionMerkleProof{BlockHeight: 170, Pos: 1, Merkle: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+			}},
+			root: "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff",
+		},
+		{
+			name: "block 100000",
+			txID: block100000TxIDs[2],
+			proof: &pkg.TransactionMerkleProof{BlockHeight: 100000, Pos: 2, Merkle: []string{
+				"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			}},
+			root: block100000MerkleRoot,
+		},
+		{
+			// The first three transactions of block 100000: the last one is
+			// paired with itself.
+			name: "odd level",
+			txID: block100000TxIDs[2],
+			proof: &pkg.TransactionMerkleProof{Pos: 2, Merkle: []string{
+				"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			}},
+			root: "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553",
+		},
+	}
+
+	for _, tc := range cases {
+		if err := pkg.VerifyMerkleProof(tc.txID, tc.proof, tc.root); err != nil {
+			t.Errorf("%s: %s", tc.name, err.Error())
+		}
+	}
+}
+
+func TestVerifyMerkleProof_Invalid(t *testing.T) {
+	valid := func() *pkg.TransactionMerkleProof {
+		return &pkg.TransactionMerkleProof{Pos: 2, Merkle: []string{
+			"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+			"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+		}}
+	}
+
+	wrongPos := valid()
+	wrongPos.Pos = 3
+	outOfRange := valid()
+	outOfRange.Pos = 4
+	tampered := valid()
+	tampered.Merkle[0] = "e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1e"
+	// Proving a phantom fourth transaction that duplicates the third.
+	duplicate := &pkg.TransactionMerkleProof{Pos: 3, Merkle: []string{
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+	}}
+
+	cases := []struct {
+		name  string
+		proof *pkg.TransactionMerkleProof
+		root  string
+	}{
+		{"wrong position", wrongPos, block100000MerkleRoot},
+		{"position out of range", outOfRange, block100000MerkleRoot},
+		{"tampered branch", tampered, block100000MerkleRoot},
+		{"wrong root", valid(), "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff"},
+		{"malformed root", valid(), "f3e947"},
+		{"duplicated right node", duplicate, "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553"},
+	}
+
+	for _, tc := range cases {
+		if err := pkg.VerifyMerkleProof(block100000TxIDs[2], tc.proof, tc.root); !errors.Is(err, pkg.ErrInvalidMerkleProof) {
+			t.Errorf("%s: expected ErrInvalidMerkleProof, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestGetVerifiedTransaction(t *testing.T) {
+	fake := esploratest.New()
+	block := &pkg.Block{ID: "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506", Height: 100000, MerkleRoot: block100000MerkleRoot}
+	txs := make([]*pkg.Transaction, 0, len(block100000TxIDs))
+	for _, txID := range block100000TxIDs {
+		txs = append(txs, &pkg.Transaction{ID: txID})
+	}
+	fake.AddBlock(block, txs...)
+	fake.AddTransaction(&pkg.Transaction{ID: "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899"})
+
+	for _, txID := range block100000TxIDs {
+		tx, err := pkg.GetVerifiedTransaction(context.Background(), fake, txID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if tx.ID != txID {
+			t.Errorf("expected %s, got %s", txID, tx.ID)
+		}
+	}
+
+	if _, err := pkg.GetVerifiedTransaction(context.Background(), fake, "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899"); !errors.Is(err, pkg.ErrTxUnconfirmed) {
+		t.Errorf("expected ErrTxUnconfirmed, got %v", err)
+	}
+
+	block.MerkleRoot = "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff"
+	fake.AddBlock(block, txs...)
+	if _, err := pkg.GetVerifiedTransaction(context.Background(), fake, block100000TxIDs[0]); !errors.Is(err, pkg.ErrInvalidMerkleProof) {
+		t.Errorf("expected ErrInvalidMerkleProof, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures an HTTPClient created with New.
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	debug     bool
+	timeout   time.Duration
+	transport http.RoundTripper
+	tlsConfig *tls.Config
+	proxy     string
+	userAgent string
+	headers   map[string]string
+	authToken string
+	username  string
+	password  string
+	basePath  string
+	retry     *RetryPolicy
+}
+
+func WithDebug(debug bool) Option {
+	return func(c *clientConfig) { c.debug = debug }
+}
+
+// WithTimeout sets the overall timeout of a single HTTP attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) { c.timeout = timeout }
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *clientConfig) { c.transport = transport }
+}
+
+// WithTLSConfig only takes effect when the transport is an *http.Transport.
+// Combined with a WithTransport of any other type it is silently ignored;
+// configure TLS on that transport instead.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *clientConfig) { c.tlsConfig = config }
+}
+
+// WithProxy, like WithTLSConfig, has no effect when WithTransport supplies
+// anything other than an *http.Transport.
+func WithProxy(proxyURL string) Option {
+	return func(c *clientConfig) { c.proxy = proxyURL }
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *clientConfig) { c.userAgent = userAgent }
+}
+
+func WithHeader(header, value string) Option {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string)
+		}
+		c.headers[header] = value
+	}
+}
+
+func WithBasicAuth(username, password string) Option {
+	return func(c *clientConfig) { c.username, c.password = username, password }
+}
+
+// WithAuthToken sends the token as an "Authorization: Bearer" header.
+func WithAuthToken(token string) Option {
+	return func(c *clientConfig) { c.authToken = token }
+}
+
+// WithBasePath prefixes every request path, e.g. "/api" or "/testnet/api".
+func WithBasePath(basePath string) Option {
+	return func(c *clientConfig) { c.basePath = basePath }
+}
+
+// WithRetryPolicy enables retries of GET requests; nil, the default,
+// disables them.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(c *clientConfig) { c.retry = policy }
+}
+
+func New(baseURL string, opts ...Option) *HTTPClient {
+	config := &clientConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	restClient := resty.New()
+	if config.transport != nil {
+		restClient.SetTransport(config.transport)
+	}
+	if _, ok := config.transport.(*http.Transport); ok || config.transport == nil {
+		if config.tlsConfig != nil {
+			restClient.SetTLSClientConfig(config.tlsConfig)
+		}
+		if config.proxy != "" {
+			restClient.SetProxy(config.proxy)
+		}
+	}
+	if config.timeout > 0 {
+		restClient.SetTimeout(config.timeout)
+	}
+	restClient.SetDebug(config.debug)
+	restClient.SetHostURL(joinURL(baseURL, config.basePath))
+	restClient.SetHeader("Accept", "application/json")
+	if config.userAgent != "" {
+		restClient.SetHeader("User-Agent", config.userAgent)
+	}
+	restClient.SetHeaders(config.headers)
+	if config.authToken != "" {
+		restClient.SetAuthToken(config.authToken)
+	}
+	if config.username != "" || config.password != "" {
+		restClient.SetBasicAuth(config.username, config.password)
+	}
+	restClient.SetContentLength(true)
+	return &HTTPClient{Client: restClient, retry: config.retry}
+}
+
+func joinURL(baseURL, basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return baseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + basePath
+}
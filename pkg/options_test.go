@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/testnet/api/blocks/tip/height" ||
+			r.Header.Get("User-Agent") != "electrs-client-test" ||
+			r.Header.Get("X-Api-Key") != "secret" ||
+			user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("1234"))
+	}))
+	defer server.Close()
+
+	client := New(server.URL+"/",
+		WithBasePath("/testnet/api/"),
+		WithUserAgent("electrs-client-test"),
+		WithHeader("X-Api-Key", "secret"),
+		WithBasicAuth("user", "pass"),
+	)
+	height, err := client.GetLastBlockHeight()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if height != 1234 {
+		t.Errorf("invalid block height")
+	}
+}
+
+func TestNew_WithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, WithTimeout(20*time.Millisecond)).GetLastBlockHash()
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("expected *TransportError, got %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNew_WithTransport(t *testing.T) {
+	var called bool
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	if _, err := New(server.URL, WithTransport(transport)).GetLastBlockHeight(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !called {
+		t.Errorf("custom transport not used")
+	}
+}
@@ -0,0 +1,240 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+type RecorderMode int
+
+const (
+	// ModeRecord forwards requests to Transport and writes every exchange
+	// to Dir.
+	ModeRecord RecorderMode = iota
+	// ModeReplay serves exchanges from Dir and never touches the network.
+	ModeReplay
+)
+
+var ErrReplayMiss = errors.New("no recorded response")
+
+// ReplayMissError is returned by a replaying Recorder for a request that was
+// never recorded. It matches ErrReplayMiss with errors.Is.
+type ReplayMissError struct {
+	Key  string
+	File string
+}
+
+func (e *ReplayMissError) Error() string {
+	return fmt.Sprintf("%s for %s (expected %s)", ErrReplayMiss.Error(), e.Key, e.File)
+}
+
+func (e *ReplayMissError) Is(target error) bool {
+	return target == ErrReplayMiss
+}
+
+// Recorder is an http.RoundTripper capturing electrs responses as golden
+// files and replaying them. Install it with WithTransport.
+//
+// Requests are keyed by method, path, query parameters in sorted order and a
+// hash of the body, so the host the client points at does not matter.
+// Repeated requests with the same key are stored and replayed in order; a
+// request beyond the recorded ones fails with a *ReplayMissError, so that a
+// test notices the extra call.
+type Recorder struct {
+	Mode RecorderMode
+	Dir  string
+	// Transport performs the real requests in ModeRecord; defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// RedactHeaders are replaced by "REDACTED" in the golden files.
+	RedactHeaders []string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func NewRecorder(dir string, mode RecorderMode, redactHeaders ...string) *Recorder {
+	return &Recorder{Mode: mode, Dir: dir, RedactHeaders: redactHeaders}
+}
+
+type recordedExchange struct {
+	Request  recordedMessage `json:"request"`
+	Response recordedMessage `json:"response"`
+}
+
+type recordedMessage struct {
+	Method       string      `json:"method,omitempty"`
+	URL          string      `json:"url,omitempty"`
+	StatusCode   int         `json:"status_code,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RoundTrip consumes and closes the body of req but otherwise leaves it
+// unchanged, as http.RoundTripper requires; the forwarded request is a clone.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	key := recorderKey(req, reqBody)
+	if r.Mode == ModeReplay {
+		return r.replay(req, key)
+	}
+	return r.record(req, key, reqBody)
+}
+
+func (r *Recorder) record(req *http.Request, key string, reqBody []byte) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	exchange := recordedExchange{
+		Request:  recordedMessage{Method: req.Method, URL: key, Header: r.redact(req.Header)},
+		Response: recordedMessage{StatusCode: resp.StatusCode, Header: r.redact(resp.Header)},
+	}
+	exchange.Request.Body, exchange.Request.BodyEncoding = encodeBody(reqBody)
+	exchange.Response.Body, exchange.Response.BodyEncoding = encodeBody(respBody)
+
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(r.file(key, r.next(key)), data, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, key string) (*http.Response, error) {
+	file := r.file(key, r.next(key))
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, &ReplayMissError{Key: key, File: file}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var exchange recordedExchange
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %w", file, err)
+	}
+	body, err := decodeBody(exchange.Response.Body, exchange.Response.BodyEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %w", file, err)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(exchange.Response.StatusCode) + " " + http.StatusText(exchange.Response.StatusCode),
+		StatusCode:    exchange.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        exchange.Response.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) next(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	n := r.calls[key]
+	r.calls[key] = n + 1
+	return n
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (r *Recorder) file(key string, n int) string {
+	sum := sha256.Sum256([]byte(key))
+	name := unsafeFileChars.ReplaceAllString(key, "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	name = fmt.Sprintf("%s_%s", name, hex.EncodeToString(sum[:4]))
+	if n > 0 {
+		name = fmt.Sprintf("%s.%d", name, n)
+	}
+	return filepath.Join(r.Dir, name+".json")
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range r.RedactHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+// recorderKey normalises a request into "METHOD /path?sorted=query", with a
+// body hash appended for requests that carry one.
+func recorderKey(req *http.Request, body []byte) string {
+	key := req.Method + " " + req.URL.EscapedPath()
+	if query := req.URL.Query().Encode(); query != "" {
+		key += "?" + query
+	}
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		key += " body=" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
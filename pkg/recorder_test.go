@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_RecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	server := newFixtureServer()
+	recorder := NewRecorder(dir, ModeRecord, "Authorization")
+	recording := New(server.URL, WithTransport(recorder), WithAuthToken("secret"))
+	if _, err := recording.GetTransaction("6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := recording.GetTransaction(notFoundTxID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 golden files, got %d", len(files))
+	}
+	for _, file := range files {
+		data, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(data), "secret") {
+			t.Errorf("%s: Authorization header not redacted", file)
+		}
+	}
+
+	replaying := New("http://electrs.invalid", WithTransport(NewRecorder(dir, ModeReplay)))
+	transaction, err := replaying.GetTransaction("6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if transaction.ID != "6b1d869856d857484ab0ac53575ac88f9a123616e22725deff7542537c827899" {
+		t.Errorf("invalid transaction id")
+	}
+	if _, err := replaying.GetTransaction(notFoundTxID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected replayed ErrNotFound, got %v", err)
+	}
+
+	_, err = replaying.GetLastBlockHash()
+	if !errors.Is(err, ErrReplayMiss) {
+		t.Fatalf("expected ErrReplayMiss, got %v", err)
+	}
+}
+
+func TestRecorder_SequenceAndQueryOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	heights := []string{"100", "101"}
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(heights[calls]))
+		calls++
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(dir, ModeRecord)
+	for range heights {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/blocks/tip/height?b=2&a=1", nil)
+		if _, err := recorder.RoundTrip(req); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	replayer := NewRecorder(dir, ModeReplay)
+	for _, expected := range heights {
+		req, _ := http.NewRequest(http.MethodGet, "http://other/blocks/tip/height?a=1&b=2", nil)
+		resp, err := replayer.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != expected {
+			t.Errorf("expected %s, got %s", expected, body)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://other/blocks/tip/height?a=1&b=2", nil)
+	if _, err := replayer.RoundTrip(req); !errors.Is(err, ErrReplayMiss) {
+		t.Errorf("expected ErrReplayMiss once the recordings are used up, got %v", err)
+	}
+}
+
+func TestRecorder_RequestUnchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+		_, _ = w.Write([]byte("txid"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/tx", strings.NewReader("0100"))
+	body := req.Body
+	if _, err := NewRecorder(dir, ModeRecord).RoundTrip(req); err != nil {
+		t.Fatal(err.Error())
+	}
+	if req.Body != body {
+		t.Errorf("RoundTrip replaced the body of the caller's request")
+	}
+	if received != "0100" {
+		t.Errorf("expected the body to be forwarded, got %q", received)
+	}
+}
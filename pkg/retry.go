@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how idempotent GET requests are retried. Broadcasts
+// are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay caps every delay, including a 429's Retry-After; 0 means
+	// no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0..1) of each delay that is randomised;
+	// values outside that range are clamped to it.
+	Jitter float64
+
+	RetryStatusCodes     []int
+	RetryTransportErrors bool
+	// RetryIf, when set, is consulted for errors the fields above reject.
+	RetryIf func(err error) bool
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.5,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryTransportErrors: true,
+	}
+}
+
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	if p == nil || p.MaxAttempts <= 1 {
+		return fn()
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !p.shouldRetry(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("request canceled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(err error) bool {
+	var apiErr *APIError
+	var transportErr *TransportError
+	switch {
+	case errors.As(err, &apiErr):
+		for _, code := range p.RetryStatusCodes {
+			if apiErr.StatusCode == code {
+				return true
+			}
+		}
+	case errors.As(err, &transportErr):
+		if p.RetryTransportErrors {
+			return true
+		}
+	}
+	return p.RetryIf != nil && p.RetryIf(err)
+}
+
+// delay returns the exponential backoff for the given attempt, or the
+// server-provided Retry-After of a 429 response, capped at MaxDelay.
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(apiErr.Header.Get("Retry-After")); ok {
+			return p.clamp(d)
+		}
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	d = p.clamp(d)
+	jitter := math.Min(p.Jitter, 1)
+	if jitter > 0 && d > 0 {
+		spread := time.Duration(float64(d) * jitter)
+		d = d - spread + time.Duration(rand.Int63n(int64(spread)+1))
+	}
+	return d
+}
+
+func (p *RetryPolicy) clamp(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
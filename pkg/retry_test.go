@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryPolicy_RetriesUnavailable(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("654321"))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, WithRetryPolicy(testRetryPolicy()))
+	height, err := client.GetLastBlockHeight()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if height != 654321 || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("unexpected height %d after %d calls", height, calls)
+	}
+}
+
+func TestRetryPolicy_GivesUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	client := New(server.URL, WithRetryPolicy(policy))
+	_, err := client.GetLastBlockHash()
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if int(atomic.LoadInt32(&calls)) != policy.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", policy.MaxAttempts, calls)
+	}
+}
+
+func TestRetryPolicy_NoRetryOnNotFound(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, WithRetryPolicy(testRetryPolicy()))
+	_, err := client.GetTransaction("00")
+	if !errors.Is(err, ErrNotFound) || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected a single ErrNotFound attempt, got %v after %d calls", err, calls)
+	}
+}
+
+func TestRetryPolicy_NoRetryOnBroadcast(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, WithRetryPolicy(testRetryPolicy()))
+	if _, err := client.BroadcastTransaction("0200"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("broadcast must not be retried, got %d calls", calls)
+	}
+}
+
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	var calls int32
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("0000000000000000000000000000000000000000000000000000000000000000"))
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	client := New(server.URL, WithRetryPolicy(policy))
+	if _, err := client.GetLastBlockHash(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if elapsed := time.Since(first); elapsed < time.Second {
+		t.Errorf("Retry-After not honoured, retried after %v", elapsed)
+	}
+}
+
+func TestRetryPolicy_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	policy := testRetryPolicy()
+	policy.MaxAttempts = 2
+	_, err := New(url, WithRetryPolicy(policy)).GetLastBlockHash()
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("expected *TransportError, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy()
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+	policy.Jitter = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := New(server.URL, WithRetryPolicy(policy)).GetLastBlockHashCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 400 * time.Millisecond}
+	expected := []time.Duration{100, 200, 400, 400}
+	for i, d := range expected {
+		if got := policy.delay(i+1, errors.New("x")); got != d*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, d*time.Millisecond, got)
+		}
+	}
+
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"86400"}}}
+	if got := policy.delay(1, rateLimited); got != 400*time.Millisecond {
+		t.Errorf("expected Retry-After capped at 400ms, got %v", got)
+	}
+
+	unlimited := &RetryPolicy{BaseDelay: time.Second}
+	if got := unlimited.delay(4, errors.New("x")); got != 8*time.Second {
+		t.Errorf("expected 8s without MaxDelay, got %v", got)
+	}
+	if got := unlimited.delay(1000, errors.New("x")); got <= 0 {
+		t.Errorf("expected no overflow, got %v", got)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(1, errors.New("x")); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("jittered delay out of range: %v", got)
+		}
+	}
+	policy.Jitter = 3
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(1, errors.New("x")); got < 0 || got > 100*time.Millisecond {
+			t.Fatalf("jitter above 1 not clamped: %v", got)
+		}
+	}
+}
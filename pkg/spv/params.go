@@ -0,0 +1,95 @@
+package spv
+
+import (
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"math/big"
+)
+
+// Params are the consensus rules the Store validates headers against.
+type Params struct {
+	Network  pkg.Network
+	PowLimit *big.Int
+	// RetargetInterval is the number of blocks between difficulty
+	// adjustments, TargetTimespan their intended duration in seconds.
+	RetargetInterval pkg.BlockHeight
+	TargetTimespan   int64
+	TargetSpacing    int64
+	// AllowMinDifficulty permits a PowLimit block when the previous one
+	// is more than two target spacings older (testnet).
+	AllowMinDifficulty bool
+	// NoRetargeting keeps the difficulty constant (regtest).
+	NoRetargeting bool
+	// Checkpoints pin block hashes at given heights. The Store starts from
+	// the highest checkpoint at a retarget boundary and rejects any chain
+	// that contradicts a checkpoint above it.
+	Checkpoints []pkg.Checkpoint
+}
+
+// DefaultParams returns the rules of network with its genesis block as the
+// only checkpoint. Add recent checkpoints with WithCheckpoints to avoid
+// downloading the whole header chain.
+//
+// Signet blocks also carry a signature in the coinbase, which cannot be
+// checked from headers alone.
+func DefaultParams(network pkg.Network) (Params, error) {
+	params := Params{
+		Network:          network,
+		PowLimit:         hexTarget("00000000ffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		RetargetInterval: 2016,
+		TargetTimespan:   14 * 24 * 60 * 60,
+		TargetSpacing:    10 * 60,
+	}
+	switch network {
+	case pkg.Mainnet:
+		params.Checkpoints = []pkg.Checkpoint{{Height: 0, Hash: "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"}}
+	case pkg.Testnet:
+		params.AllowMinDifficulty = true
+		params.Checkpoints = []pkg.Checkpoint{{Height: 0, Hash: "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"}}
+	case pkg.Signet:
+		params.PowLimit = hexTarget("00000377ae000000000000000000000000000000000000000000000000000000")
+		params.Checkpoints = []pkg.Checkpoint{{Height: 0, Hash: "00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6"}}
+	case pkg.Regtest:
+		params.PowLimit = hexTarget("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		params.AllowMinDifficulty = true
+		params.NoRetargeting = true
+		params.Checkpoints = []pkg.Checkpoint{{Height: 0, Hash: "0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206"}}
+	default:
+		return Params{}, fmt.Errorf("spv: unsupported network %s", network)
+	}
+	return params, nil
+}
+
+// base returns the checkpoint the Store starts from.
+func (p *Params) base() (pkg.Checkpoint, error) {
+	var base *pkg.Checkpoint
+	for i, checkpoint := range p.Checkpoints {
+		if checkpoint.Height%p.RetargetInterval != 0 && !p.NoRetargeting {
+			continue
+		}
+		if base == nil || checkpoint.Height > base.Height {
+			base = &p.Checkpoints[i]
+		}
+	}
+	if base == nil {
+		return pkg.Checkpoint{}, fmt.Errorf("spv: no checkpoint at a multiple of %d blocks", p.RetargetInterval)
+	}
+	return *base, nil
+}
+
+func (p *Params) checkpoint(height pkg.BlockHeight) (pkg.BlockHash, bool) {
+	for _, checkpoint := range p.Checkpoints {
+		if checkpoint.Height == height {
+			return checkpoint.Hash, true
+		}
+	}
+	return "", false
+}
+
+func hexTarget(s string) *big.Int {
+	target, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("spv: invalid target " + s)
+	}
+	return target
+}
@@ -0,0 +1,59 @@
+package spv
+
+import (
+	"context"
+	"github.com/panda-next-team/electrs-client/pkg"
+)
+
+// HeaderSource supplies serialized headers of the server's best chain.
+type HeaderSource interface {
+	// GetHeaders returns up to count consecutive headers starting at
+	// start, and none past the server's tip. It may return fewer before
+	// the tip when the server caps the count.
+	GetHeaders(ctx context.Context, start pkg.BlockHeight, count int) ([]pkg.BlockHeaderHex, error)
+}
+
+type restSource struct {
+	api pkg.BlockAPI
+}
+
+// RESTSource fetches headers one by one through /block-height/:height and
+// /block/:hash/header.
+func RESTSource(api pkg.BlockAPI) HeaderSource {
+	return &restSource{api: api}
+}
+
+func (s *restSource) GetHeaders(ctx context.Context, start pkg.BlockHeight, count int) ([]pkg.BlockHeaderHex, error) {
+	tip, err := s.api.GetLastBlockHeightCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]pkg.BlockHeaderHex, 0)
+	for height := start; height <= tip && len(headers) < count; height++ {
+		hash, err := s.api.GetBlockHashCtx(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		header, err := s.api.GetBlockHeaderCtx(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+type electrumSource struct {
+	client *pkg.ElectrumClient
+}
+
+// ElectrumSource fetches headers in batches through
+// blockchain.block.headers.
+func ElectrumSource(client *pkg.ElectrumClient) HeaderSource {
+	return &electrumSource{client: client}
+}
+
+func (s *electrumSource) GetHeaders(ctx context.Context, start pkg.BlockHeight, count int) ([]pkg.BlockHeaderHex, error) {
+	return s.client.GetBlockHeadersCtx(ctx, start, count)
+}
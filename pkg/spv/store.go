@@ -0,0 +1,497 @@
+// Package spv keeps a validated chain of block headers so that
+// confirmations reported by an electrs server can be checked instead of
+// trusted.
+package spv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrInvalidHeader      = errors.New("spv: invalid header")
+	ErrCheckpointMismatch = errors.New("spv: header contradicts checkpoint")
+	ErrLessWork           = errors.New("spv: server chain has less work than the stored chain")
+	ErrUnknownHeight      = errors.New("spv: no stored header at height")
+)
+
+const (
+	fileMagic       = "SPV1"
+	fileHeaderLen   = 8
+	blockHeaderLen  = 80
+	medianTimeSpan  = 11
+	defaultMaxReorg = 100
+)
+
+type Option func(*storeConfig)
+
+type storeConfig struct {
+	checkpoints   []pkg.Checkpoint
+	maxReorgDepth int
+	batchSize     int
+}
+
+// WithCheckpoints adds checkpoints to the network params.
+func WithCheckpoints(checkpoints ...pkg.Checkpoint) Option {
+	return func(c *storeConfig) { c.checkpoints = append(c.checkpoints, checkpoints...) }
+}
+
+// MaxReorgDepth bounds how far back Sync looks for a fork point.
+func MaxReorgDepth(depth int) Option {
+	return func(c *storeConfig) { c.maxReorgDepth = depth }
+}
+
+// BatchSize sets how many headers Sync requests at once. Sources may return
+// fewer, e.g. Electrum servers at most 2016.
+func BatchSize(size int) Option {
+	return func(c *storeConfig) { c.batchSize = size }
+}
+
+// Store is a header chain persisted to a local file. It starts at a
+// checkpoint, accepts only headers that link up, meet their proof of work
+// and follow the difficulty rules, and switches forks only to a chain with
+// more work. Sync extends it from a HeaderSource; Confirmations combines it
+// with merkle proofs.
+type Store struct {
+	params Params
+	config storeConfig
+	base   pkg.Checkpoint
+	file   *os.File
+
+	syncMu  sync.Mutex
+	mu      sync.RWMutex
+	headers []*pkg.BlockHeader
+	index   map[pkg.BlockHash]pkg.BlockHeight
+}
+
+// Open loads the chain stored at path, creating the file if needed. Stored
+// headers are validated again; anything from the first invalid one on is
+// discarded and downloaded again by the next Sync.
+func Open(path string, params Params, opts ...Option) (*Store, error) {
+	config := storeConfig{maxReorgDepth: defaultMaxReorg, batchSize: 2016}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	params.Checkpoints = append(append([]pkg.Checkpoint(nil), params.Checkpoints...), config.checkpoints...)
+	base, err := params.base()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{params: params, config: config, base: base, file: file, index: make(map[pkg.BlockHash]pkg.BlockHeight)}
+	if err := s.load(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	data, err := ioutil.ReadAll(s.file)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		_, err := s.file.WriteAt(s.fileHeader(), 0)
+		return err
+	}
+	if len(data) < fileHeaderLen || string(data[:4]) != fileMagic {
+		return fmt.Errorf("spv: %s is not a header store", s.file.Name())
+	}
+	if height := pkg.BlockHeight(uint32(data[4]) | uint32(data[5])<<8 | uint32(data[6])<<16 | uint32(data[7])<<24); height != s.base.Height {
+		return fmt.Errorf("spv: %s starts at height %d, params start at %d", s.file.Name(), height, s.base.Height)
+	}
+
+	v := &view{base: s.base.Height}
+	for offset := fileHeaderLen; offset+blockHeaderLen <= len(data); offset += blockHeaderLen {
+		header, err := pkg.DecodeBlockHeader(data[offset : offset+blockHeaderLen])
+		if err != nil || v.connect(&s.params, s.base, header) != nil {
+			break
+		}
+	}
+	if err := s.file.Truncate(s.offset(s.base.Height + pkg.BlockHeight(len(v.branch)))); err != nil {
+		return err
+	}
+	s.commit(s.base.Height-1, v.branch)
+	return nil
+}
+
+func (s *Store) fileHeader() []byte {
+	height := uint32(s.base.Height)
+	return []byte{fileMagic[0], fileMagic[1], fileMagic[2], fileMagic[3], byte(height), byte(height >> 8), byte(height >> 16), byte(height >> 24)}
+}
+
+func (s *Store) offset(height pkg.BlockHeight) int64 {
+	return fileHeaderLen + int64(height-s.base.Height)*blockHeaderLen
+}
+
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+// Tip returns the last stored header; the zero Checkpoint before the first
+// Sync.
+func (s *Store) Tip() pkg.Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.headers) == 0 {
+		return pkg.Checkpoint{}
+	}
+	return pkg.Checkpoint{Height: s.tip(), Hash: s.headers[len(s.headers)-1].Hash()}
+}
+
+func (s *Store) Header(height pkg.BlockHeight) (*pkg.BlockHeader, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := int(height - s.base.Height)
+	if i < 0 || i >= len(s.headers) {
+		return nil, false
+	}
+	return s.headers[i], true
+}
+
+// Height returns the height of a block on the stored chain.
+func (s *Store) Height(hash pkg.BlockHash) (pkg.BlockHeight, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	height, ok := s.index[hash]
+	return height, ok
+}
+
+// Confirmations fetches the merkle proof of txID and verifies it against the
+// stored header at the proven height. It returns 0 for a transaction the
+// server reports as unconfirmed or unknown, and ErrUnknownHeight when the
+// proof points outside the stored chain, e.g. before the Store caught up.
+func (s *Store) Confirmations(ctx context.Context, api pkg.TxAPI, txID pkg.TxID) (int32, error) {
+	proof, err := api.GetTransactionMerkleProofCtx(ctx, txID)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	header, ok := s.Header(proof.BlockHeight)
+	if !ok {
+		return 0, fmt.Errorf("%w %d (stored %d-%d)", ErrUnknownHeight, proof.BlockHeight, s.base.Height, s.Tip().Height)
+	}
+	if err := pkg.VerifyMerkleProof(txID, proof, header.MerkleRoot); err != nil {
+		return 0, err
+	}
+	return int32(s.Tip().Height-proof.BlockHeight) + 1, nil
+}
+
+// IsConfirmed reports whether txID has at least n confirmations on the
+// stored chain.
+func (s *Store) IsConfirmed(ctx context.Context, api pkg.TxAPI, txID pkg.TxID, n int32) (bool, error) {
+	confirmations, err := s.Confirmations(ctx, api, txID)
+	if err != nil {
+		return false, err
+	}
+	return confirmations >= n, nil
+}
+
+// Sync downloads headers from src until it reaches the source's tip. When
+// the source is on a different branch, Sync finds the fork point, at most
+// MaxReorgDepth blocks back, and switches only if that branch has more
+// work; otherwise it returns ErrLessWork. A branch of equal length to the
+// stored one is only noticed once it grows past it.
+func (s *Store) Sync(ctx context.Context, src HeaderSource) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	for {
+		done, err := s.syncBatch(ctx, src)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (s *Store) syncBatch(ctx context.Context, src HeaderSource) (bool, error) {
+	s.mu.RLock()
+	stored := s.headers
+	s.mu.RUnlock()
+
+	fork := s.base.Height + pkg.BlockHeight(len(stored)) - 1
+	headers, err := fetch(ctx, src, fork+1, s.config.batchSize)
+	if err != nil {
+		return false, err
+	}
+	if len(headers) == 0 {
+		return true, nil
+	}
+	if len(stored) > 0 && headers[0].PreviousBlockHash != stored[len(stored)-1].Hash() {
+		if fork, err = s.findFork(ctx, src, stored); err != nil {
+			return false, err
+		}
+		return true, s.switchBranch(ctx, src, stored, fork)
+	}
+
+	v := &view{base: s.base.Height, prefix: stored}
+	for _, header := range headers {
+		if err := v.connect(&s.params, s.base, header); err != nil {
+			return false, err
+		}
+	}
+	if err := s.persist(fork, v.branch); err != nil {
+		return false, err
+	}
+	// A short batch does not mean the tip was reached, since servers cap
+	// the count (Electrum at 2016); only an empty one does.
+	return false, nil
+}
+
+// findFork returns the highest height at which src agrees with stored.
+func (s *Store) findFork(ctx context.Context, src HeaderSource, stored []*pkg.BlockHeader) (pkg.BlockHeight, error) {
+	tip := s.base.Height + pkg.BlockHeight(len(stored)) - 1
+	for height := tip - 1; height >= s.base.Height; height-- {
+		if int(tip-height) > s.config.maxReorgDepth {
+			break
+		}
+		headers, err := fetch(ctx, src, height, 1)
+		if err != nil {
+			return 0, err
+		}
+		if len(headers) == 1 && headers[0].Hash() == stored[height-s.base.Height].Hash() {
+			return height, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no common block with the source within %d blocks of %d", pkg.ErrReorgTooDeep, s.config.maxReorgDepth, tip)
+}
+
+// switchBranch downloads the source's branch above fork and replaces the
+// stored one with it if it has more work.
+func (s *Store) switchBranch(ctx context.Context, src HeaderSource, stored []*pkg.BlockHeader, fork pkg.BlockHeight) error {
+	prefix := stored[:fork-s.base.Height+1]
+	v := &view{base: s.base.Height, prefix: prefix}
+	for {
+		headers, err := fetch(ctx, src, v.tip()+1, s.config.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			break
+		}
+		for _, header := range headers {
+			if err := v.connect(&s.params, s.base, header); err != nil {
+				return err
+			}
+		}
+	}
+
+	if chainWork(v.branch).Cmp(chainWork(stored[len(prefix):])) <= 0 {
+		return fmt.Errorf("%w: fork at %d", ErrLessWork, fork)
+	}
+	return s.persist(fork, v.branch)
+}
+
+// persist replaces everything above fork with branch, on disk and then in
+// memory.
+func (s *Store) persist(fork pkg.BlockHeight, branch []*pkg.BlockHeader) error {
+	data := make([]byte, 0, len(branch)*blockHeaderLen)
+	for _, header := range branch {
+		data = append(data, header.Raw...)
+	}
+	if err := s.file.Truncate(s.offset(fork + 1)); err != nil {
+		return err
+	}
+	if _, err := s.file.WriteAt(data, s.offset(fork+1)); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.commit(fork, branch)
+	return nil
+}
+
+func (s *Store) commit(fork pkg.BlockHeight, branch []*pkg.BlockHeader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keep := int(fork - s.base.Height + 1)
+	for _, header := range s.headers[keep:] {
+		delete(s.index, header.Hash())
+	}
+	s.headers = append(s.headers[:keep:keep], branch...)
+	for i, header := range branch {
+		s.index[header.Hash()] = fork + 1 + pkg.BlockHeight(i)
+	}
+}
+
+func (s *Store) tip() pkg.BlockHeight {
+	return s.base.Height + pkg.BlockHeight(len(s.headers)) - 1
+}
+
+func fetch(ctx context.Context, src HeaderSource, start pkg.BlockHeight, count int) ([]*pkg.BlockHeader, error) {
+	raw, err := src.GetHeaders(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*pkg.BlockHeader, 0, len(raw))
+	for _, headerHex := range raw {
+		header, err := headerHex.Decode()
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// view is a candidate chain: a stored prefix extended by a new branch,
+// without copying the prefix.
+type view struct {
+	base   pkg.BlockHeight
+	prefix []*pkg.BlockHeader
+	branch []*pkg.BlockHeader
+}
+
+func (v *view) len() int {
+	return len(v.prefix) + len(v.branch)
+}
+
+func (v *view) tip() pkg.BlockHeight {
+	return v.base + pkg.BlockHeight(v.len()) - 1
+}
+
+func (v *view) at(height pkg.BlockHeight) *pkg.BlockHeader {
+	i := int(height - v.base)
+	if i < 0 || i >= v.len() {
+		return nil
+	}
+	if i < len(v.prefix) {
+		return v.prefix[i]
+	}
+	return v.branch[i-len(v.prefix)]
+}
+
+// connect validates header as the next block of the view and appends it.
+func (v *view) connect(params *Params, base pkg.Checkpoint, header *pkg.BlockHeader) error {
+	height := v.tip() + 1
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w at height %d: %s", ErrInvalidHeader, height, fmt.Sprintf(format, args...))
+	}
+
+	target, err := header.Target()
+	if err != nil {
+		return invalid("%s", err.Error())
+	}
+	if target.Cmp(params.PowLimit) > 0 {
+		return invalid("target above the network limit")
+	}
+	if err := header.CheckProofOfWork(); err != nil {
+		return invalid("%s", err.Error())
+	}
+
+	if height > base.Height {
+		prev := v.at(height - 1)
+		if header.PreviousBlockHash != prev.Hash() {
+			return invalid("previous block %s, expected %s", header.PreviousBlockHash, prev.Hash())
+		}
+		bits, err := v.nextBits(params, header)
+		if err != nil {
+			return invalid("%s", err.Error())
+		}
+		if header.Bits != bits {
+			return invalid("bits 0x%08x, expected 0x%08x", header.Bits, bits)
+		}
+		if median, ok := v.medianTime(); ok && header.Timestamp <= median {
+			return invalid("timestamp %d not after median time %d", header.Timestamp, median)
+		}
+	}
+
+	if hash, ok := params.checkpoint(height); ok && header.Hash() != hash {
+		return fmt.Errorf("%w at height %d: %s, expected %s", ErrCheckpointMismatch, height, header.Hash(), hash)
+	}
+	v.branch = append(v.branch, header)
+	return nil
+}
+
+// nextBits returns the difficulty the block after the view's tip must
+// have, as in bitcoind's GetNextWorkRequired.
+func (v *view) nextBits(params *Params, header *pkg.BlockHeader) (uint32, error) {
+	height := v.tip() + 1
+	prev := v.at(height - 1)
+	powLimitBits := pkg.TargetToCompact(params.PowLimit)
+
+	if height%params.RetargetInterval != 0 {
+		if !params.AllowMinDifficulty {
+			return prev.Bits, nil
+		}
+		if int64(header.Timestamp) > int64(prev.Timestamp)+2*params.TargetSpacing {
+			return powLimitBits, nil
+		}
+		// Otherwise the last difficulty that was not a min-difficulty
+		// exception.
+		h := height - 1
+		for h > v.base && h%params.RetargetInterval != 0 && v.at(h).Bits == powLimitBits {
+			h--
+		}
+		return v.at(h).Bits, nil
+	}
+	if params.NoRetargeting {
+		return prev.Bits, nil
+	}
+
+	first := v.at(height - params.RetargetInterval)
+	if first == nil {
+		return 0, fmt.Errorf("retarget needs the header at %d", height-params.RetargetInterval)
+	}
+	timespan := int64(prev.Timestamp) - int64(first.Timestamp)
+	if timespan < params.TargetTimespan/4 {
+		timespan = params.TargetTimespan / 4
+	}
+	if timespan > params.TargetTimespan*4 {
+		timespan = params.TargetTimespan * 4
+	}
+
+	target, err := pkg.CompactToTarget(prev.Bits)
+	if err != nil {
+		return 0, err
+	}
+	target.Mul(target, big.NewInt(timespan))
+	target.Div(target, big.NewInt(params.TargetTimespan))
+	if target.Cmp(params.PowLimit) > 0 {
+		target = params.PowLimit
+	}
+	return pkg.TargetToCompact(target), nil
+}
+
+// medianTime returns the median timestamp of the last 11 blocks, which the
+// next block must exceed.
+func (v *view) medianTime() (uint32, bool) {
+	if v.len() < medianTimeSpan {
+		return 0, false
+	}
+	times := make([]uint32, 0, medianTimeSpan)
+	for height := v.tip(); height > v.tip()-medianTimeSpan; height-- {
+		times = append(times, v.at(height).Timestamp)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[medianTimeSpan/2], true
+}
+
+// chainWork sums the expected number of hashes behind headers.
+func chainWork(headers []*pkg.BlockHeader) *big.Int {
+	work := new(big.Int)
+	limit := new(big.Int).Lsh(big.NewInt(1), 256)
+	for _, header := range headers {
+		target, err := header.Target()
+		if err != nil {
+			continue
+		}
+		work.Add(work, new(big.Int).Div(limit, target.Add(target, big.NewInt(1))))
+	}
+	return work
+}
@@ -0,0 +1,371 @@
+package spv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testGenesisTime = 1600000000
+
+func testParams(interval pkg.BlockHeight) Params {
+	return Params{
+		Network:          pkg.Regtest,
+		PowLimit:         hexTarget("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		RetargetInterval: interval,
+		TargetTimespan:   int64(interval) * 600,
+		TargetSpacing:    600,
+	}
+}
+
+// mine finds a nonce for the header built from block.
+func mine(t *testing.T, block *pkg.Block) *pkg.BlockHeader {
+	for ; ; block.Nonce++ {
+		header, err := pkg.NewBlockHeader(block)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if header.CheckProofOfWork() == nil {
+			return header
+		}
+	}
+}
+
+// extend mines n blocks on top of chain, spacing seconds apart, with the
+// bits the difficulty rules require. tag makes merkle roots, and so hashes,
+// differ between branches.
+func extend(t *testing.T, params Params, chain []*pkg.BlockHeader, n int, spacing uint32, tag int) []*pkg.BlockHeader {
+	v := &view{prefix: chain}
+	for i := 0; i < n; i++ {
+		height := v.tip() + 1
+		block := &pkg.Block{Version: 1, MerkleRoot: fmt.Sprintf("%064x", tag<<32|int(height)), Timestamp: testGenesisTime, Bits: 0x207fffff}
+		if height > 0 {
+			prev := v.at(height - 1)
+			block.PreviousBlockHash = prev.Hash()
+			block.Timestamp = int64(prev.Timestamp + spacing)
+			bits, err := v.nextBits(&params, &pkg.BlockHeader{Timestamp: uint32(block.Timestamp)})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			block.Bits = int64(bits)
+		}
+		if err := v.connect(&params, pkg.Checkpoint{}, mine(t, block)); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	return append(append([]*pkg.BlockHeader(nil), chain...), v.branch...)
+}
+
+func withGenesis(params Params, chain []*pkg.BlockHeader) Params {
+	params.Checkpoints = []pkg.Checkpoint{{Height: 0, Hash: chain[0].Hash()}}
+	return params
+}
+
+type memorySource []*pkg.BlockHeader
+
+func (s memorySource) GetHeaders(ctx context.Context, start pkg.BlockHeight, count int) ([]pkg.BlockHeaderHex, error) {
+	headers := make([]pkg.BlockHeaderHex, 0)
+	for height := int(start); height < len(s) && len(headers) < count; height++ {
+		headers = append(headers, s[height].Hex())
+	}
+	return headers, nil
+}
+
+// cappedSource returns at most max headers per request, like an Electrum
+// server does with 2016.
+type cappedSource struct {
+	memorySource
+	max int
+}
+
+func (s cappedSource) GetHeaders(ctx context.Context, start pkg.BlockHeight, count int) ([]pkg.BlockHeaderHex, error) {
+	if count > s.max {
+		count = s.max
+	}
+	return s.memorySource.GetHeaders(ctx, start, count)
+}
+
+func tempStore(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "spv")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return filepath.Join(dir, "headers"), func() { _ = os.RemoveAll(dir) }
+}
+
+func TestStore_SyncAndReopen(t *testing.T) {
+	path, cleanup := tempStore(t)
+	defer cleanup()
+
+	params := testParams(2016)
+	chain := extend(t, params, nil, 10, 600, 0)
+	params = withGenesis(params, chain)
+
+	s, err := Open(path, params, BatchSize(3))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := s.Sync(context.Background(), memorySource(chain)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 9 || tip.Hash != chain[9].Hash() {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+	if header, ok := s.Header(5); !ok || header.Hash() != chain[5].Hash() {
+		t.Errorf("unexpected header at 5")
+	}
+	if height, ok := s.Height(chain[7].Hash()); !ok || height != 7 {
+		t.Errorf("unexpected height %d", height)
+	}
+	_ = s.Close()
+
+	s, err = Open(path, params)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+	if tip := s.Tip(); tip.Height != 9 || tip.Hash != chain[9].Hash() {
+		t.Errorf("unexpected tip after reopen %+v", tip)
+	}
+
+	chain = extend(t, params, chain, 2, 600, 0)
+	if err := s.Sync(context.Background(), memorySource(chain)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 11 {
+		t.Errorf("unexpected tip after second sync %+v", tip)
+	}
+}
+
+func TestStore_Retarget(t *testing.T) {
+	params := testParams(4)
+	// Blocks a quarter of the target spacing apart: the difficulty rises
+	// fourfold, the most a single retarget allows.
+	chain := extend(t, params, nil, 6, 150, 0)
+	if chain[3].Bits != 0x207fffff || chain[4].Bits != 0x201fffff {
+		t.Fatalf("unexpected bits 0x%08x 0x%08x", chain[3].Bits, chain[4].Bits)
+	}
+
+	path, cleanup := tempStore(t)
+	defer cleanup()
+	s, err := Open(path, withGenesis(params, chain))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+
+	// The same timestamps with the retarget skipped.
+	easy := append([]*pkg.BlockHeader(nil), chain[:4]...)
+	easy = append(easy, mine(t, &pkg.Block{
+		Version:           1,
+		PreviousBlockHash: chain[3].Hash(),
+		MerkleRoot:        chain[4].MerkleRoot,
+		Timestamp:         int64(chain[4].Timestamp),
+		Bits:              0x207fffff,
+	}))
+	if err := s.Sync(context.Background(), memorySource(easy)); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+	if err := s.Sync(context.Background(), memorySource(chain)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 5 {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+}
+
+func TestStore_Reorg(t *testing.T) {
+	params := testParams(4)
+	chain := extend(t, params, nil, 8, 600, 0)
+	params = withGenesis(params, chain)
+
+	path, cleanup := tempStore(t)
+	defer cleanup()
+	s, err := Open(path, params)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+	if err := s.Sync(context.Background(), memorySource(chain)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fork := extend(t, params, chain[:6], 4, 600, 1)
+	if err := s.Sync(context.Background(), memorySource(fork)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 9 || tip.Hash != fork[9].Hash() {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+	if _, ok := s.Height(chain[7].Hash()); ok {
+		t.Errorf("expected replaced block to be forgotten")
+	}
+
+	// Fast blocks raise the difficulty at 4; the longer branch that kept
+	// the minimum difficulty has less work.
+	hard := extend(t, params, chain[:1], 7, 150, 2)
+	s2Path, cleanup2 := tempStore(t)
+	defer cleanup2()
+	s2, err := Open(s2Path, params, MaxReorgDepth(10))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s2.Close()
+	if err := s2.Sync(context.Background(), memorySource(hard)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := s2.Sync(context.Background(), memorySource(fork)); !errors.Is(err, ErrLessWork) {
+		t.Errorf("expected ErrLessWork, got %v", err)
+	}
+	if tip := s2.Tip(); tip.Hash != hard[7].Hash() {
+		t.Errorf("expected the stored chain to be kept, tip %+v", tip)
+	}
+
+	s3Path, cleanup3 := tempStore(t)
+	defer cleanup3()
+	s3, err := Open(s3Path, params, MaxReorgDepth(2))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s3.Close()
+	if err := s3.Sync(context.Background(), memorySource(hard)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := s3.Sync(context.Background(), memorySource(fork)); !errors.Is(err, pkg.ErrReorgTooDeep) {
+		t.Errorf("expected ErrReorgTooDeep, got %v", err)
+	}
+}
+
+func TestStore_Checkpoints(t *testing.T) {
+	params := testParams(4)
+	chain := extend(t, params, nil, 10, 600, 0)
+	other := extend(t, params, chain[:4], 6, 600, 1)
+	params = withGenesis(params, chain)
+
+	path, cleanup := tempStore(t)
+	defer cleanup()
+	s, err := Open(path, params, WithCheckpoints(pkg.Checkpoint{Height: 6, Hash: chain[6].Hash()}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := s.Sync(context.Background(), memorySource(other)); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("expected ErrCheckpointMismatch, got %v", err)
+	}
+	_ = s.Close()
+
+	// Starting from a checkpoint at a retarget boundary skips the headers
+	// below it, but the file cannot be reused with a different start.
+	s, err = Open(path, params, WithCheckpoints(pkg.Checkpoint{Height: 4, Hash: chain[4].Hash()}))
+	if err == nil {
+		_ = s.Close()
+		t.Fatal("expected error for a store started at another checkpoint")
+	}
+	path, cleanup = tempStore(t)
+	defer cleanup()
+	s, err = Open(path, params, WithCheckpoints(pkg.Checkpoint{Height: 4, Hash: chain[4].Hash()}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+	if err := s.Sync(context.Background(), memorySource(chain)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := s.Header(3); ok {
+		t.Errorf("expected no header below the checkpoint")
+	}
+	if tip := s.Tip(); tip.Height != 9 {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+}
+
+func TestStore_Confirmations(t *testing.T) {
+	params := testParams(2016)
+	chain := extend(t, params, nil, 6, 600, 0)
+	params = withGenesis(params, chain)
+
+	// Each block holds one transaction whose id is the merkle root.
+	fake := esploratest.New()
+	for height, header := range chain {
+		fake.AddBlock(&pkg.Block{
+			ID:                header.Hash(),
+			Height:            pkg.BlockHeight(height),
+			Version:           header.Version,
+			Timestamp:         int64(header.Timestamp),
+			MerkleRoot:        header.MerkleRoot,
+			PreviousBlockHash: header.PreviousBlockHash,
+			Nonce:             int64(header.Nonce),
+			Bits:              int64(header.Bits),
+		}, &pkg.Transaction{ID: pkg.TxID(header.MerkleRoot)})
+	}
+
+	path, cleanup := tempStore(t)
+	defer cleanup()
+	s, err := Open(path, params, BatchSize(4))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+	if err := s.Sync(context.Background(), RESTSource(fake)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Hash != chain[5].Hash() {
+		t.Fatalf("unexpected tip %+v", tip)
+	}
+
+	confirmations, err := s.Confirmations(context.Background(), fake, pkg.TxID(chain[2].MerkleRoot))
+	if err != nil || confirmations != 4 {
+		t.Errorf("expected 4 confirmations, got %d %v", confirmations, err)
+	}
+	if ok, err := s.IsConfirmed(context.Background(), fake, pkg.TxID(chain[2].MerkleRoot), 6); err != nil || ok {
+		t.Errorf("expected fewer than 6 confirmations, got %v %v", ok, err)
+	}
+	if confirmations, err := s.Confirmations(context.Background(), fake, pkg.TxID(fmt.Sprintf("%064x", 9<<32|1))); err != nil || confirmations != 0 {
+		t.Errorf("expected 0 confirmations for an unknown tx, got %d %v", confirmations, err)
+	}
+
+	// A server claiming a transaction sits in a block it is not part of.
+	fake.AddBlock(&pkg.Block{ID: chain[3].Hash(), Height: 3, MerkleRoot: chain[3].MerkleRoot},
+		&pkg.Transaction{ID: pkg.TxID(chain[3].MerkleRoot)}, &pkg.Transaction{ID: pkg.TxID(fmt.Sprintf("%064x", 9<<32|2))})
+	if _, err := s.Confirmations(context.Background(), fake, pkg.TxID(fmt.Sprintf("%064x", 9<<32|2))); !errors.Is(err, pkg.ErrInvalidMerkleProof) {
+		t.Errorf("expected ErrInvalidMerkleProof, got %v", err)
+	}
+
+	fake.AddBlock(&pkg.Block{ID: pkg.BlockHash(fmt.Sprintf("%064x", 9<<32|3)), Height: 6}, &pkg.Transaction{ID: pkg.TxID(fmt.Sprintf("%064x", 9<<32|4))})
+	if _, err := s.Confirmations(context.Background(), fake, pkg.TxID(fmt.Sprintf("%064x", 9<<32|4))); !errors.Is(err, ErrUnknownHeight) {
+		t.Errorf("expected ErrUnknownHeight, got %v", err)
+	}
+}
+
+func TestStore_CappedSource(t *testing.T) {
+	params := testParams(2016)
+	chain := extend(t, params, nil, 10, 600, 0)
+	params = withGenesis(params, chain)
+
+	path, cleanup := tempStore(t)
+	defer cleanup()
+	s, err := Open(path, params, BatchSize(5))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer s.Close()
+	if err := s.Sync(context.Background(), cappedSource{memorySource(chain), 2}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 9 || tip.Hash != chain[9].Hash() {
+		t.Errorf("unexpected tip %+v", tip)
+	}
+
+	fork := extend(t, params, chain[:6], 6, 600, 1)
+	if err := s.Sync(context.Background(), cappedSource{memorySource(fork), 2}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if tip := s.Tip(); tip.Height != 11 || tip.Hash != fork[11].Hash() {
+		t.Errorf("unexpected tip after reorg %+v", tip)
+	}
+}
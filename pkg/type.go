@@ -1,5 +1,7 @@
 package pkg
 
+import "encoding/json"
+
 type TxID string
 type TxHex string
 type Address string
@@ -15,34 +17,48 @@ type Transaction struct {
 	VOut     []*TransactionOut `json:"vout"`
 	Size     int32             `json:"size"`
 	Weight   int32             `json:"weight"`
-	Fee      int32             `json:"fee"`
+	Fee      Satoshi           `json:"fee"`
 	Status   TransactionStatus `json:"status"`
 }
 
+// TransactionOut is an output. The commitment, asset and peg-out fields
+// are only set on Liquid, where Value is absent for confidential outputs;
+// see IsConfidential.
 type TransactionOut struct {
-	ScriptPubKey        string `json:"scriptpubkey"`
-	ScriptPubKeyAsm     string `json:"scriptpubkey_asm"`
-	ScriptPubKeyType    string `json:"scriptpubkey_type"`
-	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
-	Value               int64  `json:"value"`
+	ScriptPubKey        string  `json:"scriptpubkey"`
+	ScriptPubKeyAsm     string  `json:"scriptpubkey_asm"`
+	ScriptPubKeyType    string  `json:"scriptpubkey_type"`
+	ScriptPubKeyAddress string  `json:"scriptpubkey_address"`
+	Value               Satoshi `json:"value"`
+	ValueCommitment     string  `json:"valuecommitment,omitempty"`
+	Asset               AssetID `json:"asset,omitempty"`
+	AssetCommitment     string  `json:"assetcommitment,omitempty"`
+	Pegout              *Pegout `json:"pegout,omitempty"`
 }
 
+// TransactionIn is an input. PrevOut is zero for coinbase inputs, which the
+// API reports with a null prevout. The inner script fields are only set
+// for P2SH and P2WSH spends, IsPegin and Issuance only on Liquid.
 type TransactionIn struct {
-	ID           TxID           `json:"txid"`
-	VOut         int64          `json:"vout"`
-	PrevOut      TransactionOut `json:"prevout"`
-	ScriptSig    string         `json:"scriptsig"`
-	ScriptSigAsm string         `json:"scriptsig_asm"`
-	Witness      []string       `json:"witness"`
-	IsCoinBase   bool           `json:"isCoinBase"`
-	Sequence     int64          `json:"sequence"`
+	ID                    TxID           `json:"txid"`
+	VOut                  int64          `json:"vout"`
+	PrevOut               TransactionOut `json:"prevout"`
+	ScriptSig             string         `json:"scriptsig"`
+	ScriptSigAsm          string         `json:"scriptsig_asm"`
+	InnerRedeemScriptAsm  string         `json:"inner_redeemscript_asm,omitempty"`
+	InnerWitnessScriptAsm string         `json:"inner_witnessscript_asm,omitempty"`
+	Witness               []string       `json:"witness"`
+	IsCoinBase            bool           `json:"is_coinbase"`
+	IsPegin               bool           `json:"is_pegin,omitempty"`
+	Issuance              *Issuance      `json:"issuance,omitempty"`
+	Sequence              int64          `json:"sequence"`
 }
 
 type TransactionStatus struct {
 	Confirmed   bool        `json:"confirmed"`
 	BlockHeight BlockHeight `json:"block_height"`
 	BlockHash   string      `json:"block_hash"`
-	BlockTime   int32       `json:"block_time"`
+	BlockTime   int64       `json:"block_time"`
 }
 
 type TransactionMerkleProof struct {
@@ -51,6 +67,8 @@ type TransactionMerkleProof struct {
 	Pos         int32       `json:"pos"`
 }
 
+// TransactionOutSpend describes the spender of an output; ID, VInPos and
+// Status are absent for unspent outputs.
 type TransactionOutSpend struct {
 	Spent  bool               `json:"spent"`
 	ID     TxID               `json:"txid"`
@@ -61,44 +79,58 @@ type TransactionOutSpend struct {
 type AddressInfo struct {
 	Address    Address    `json:"address"`
 	ChainStats ChainStats `json:"chain_stats"`
-	MemStats   MemStats   `json:"mem_stats"`
+	MemStats   MemStats   `json:"mempool_stats"`
 }
 
 type ScriptHashInfo struct {
 	ScriptHash ScriptHash `json:"scripthash"`
 	ChainStats ChainStats `json:"chain_stats"`
-	MemStats   MemStats   `json:"mem_stats"`
+	MemStats   MemStats   `json:"mempool_stats"`
 }
 
 type ChainStats struct {
 	FoundedTxoCount int32   `json:"funded_txo_count"`
-	FoundedTxoSum   float64 `json:"funded_txo_sum"`
+	FoundedTxoSum   Satoshi `json:"funded_txo_sum"`
 	SpentTxoCount   int32   `json:"spent_txo_count"`
-	SpentTxoSum     float64 `json:"spent_txo_sum"`
+	SpentTxoSum     Satoshi `json:"spent_txo_sum"`
 	TxCount         int32   `json:"tx_count"`
 }
 
 type MemStats ChainStats
 
+// UnspentTransactionOutput is an unspent output. As in TransactionOut, the
+// fields after Value are only set on Liquid.
 type UnspentTransactionOutput struct {
-	ID     TxID              `json:"txid"`
-	VOut   int32             `json:"vout"`
-	Status TransactionStatus `json:"status"`
-	Value  int64             `json:"value"`
+	ID              TxID              `json:"txid"`
+	VOut            int32             `json:"vout"`
+	Status          TransactionStatus `json:"status"`
+	Value           Satoshi           `json:"value"`
+	ValueCommitment string            `json:"valuecommitment,omitempty"`
+	Asset           AssetID           `json:"asset,omitempty"`
+	AssetCommitment string            `json:"assetcommitment,omitempty"`
+	Nonce           string            `json:"nonce,omitempty"`
+	NonceCommitment string            `json:"noncecommitment,omitempty"`
+	SurjectionProof string            `json:"surjection_proof,omitempty"`
+	RangeProof      string            `json:"range_proof,omitempty"`
 }
 
+// Block is a block summary. Liquid blocks have no Nonce, Bits or
+// Difficulty; Ext carries their dynamic federation parameters instead.
 type Block struct {
-	ID                BlockHash   `json:"id"`
-	Height            BlockHeight `json:"height"`
-	Version           int32       `json:"version"`
-	Timestamp         int32       `json:"timestamp"`
-	TxCount           int32       `json:"tx_count"`
-	Size              int32       `json:"size"`
-	Weight            int32       `json:"weight"`
-	MerkleRoot        string      `json:"merkle_root"`
-	PreviousBlockHash BlockHash   `json:"previousblockhash"`
-	Nonce             int64       `json:"nonce"`
-	Bits              int64       `json:"bits"`
+	ID                BlockHash       `json:"id"`
+	Height            BlockHeight     `json:"height"`
+	Version           int32           `json:"version"`
+	Timestamp         int64           `json:"timestamp"`
+	TxCount           int32           `json:"tx_count"`
+	Size              int32           `json:"size"`
+	Weight            int32           `json:"weight"`
+	MerkleRoot        string          `json:"merkle_root"`
+	PreviousBlockHash BlockHash       `json:"previousblockhash"`
+	MedianTime        int64           `json:"mediantime"`
+	Nonce             int64           `json:"nonce"`
+	Bits              int64           `json:"bits"`
+	Difficulty        float64         `json:"difficulty"`
+	Ext               json.RawMessage `json:"ext,omitempty"`
 }
 
 type Blocks []*Block
@@ -114,17 +146,49 @@ type BlockStatus struct {
 }
 
 type MemPoolStatistics struct {
-	Count        int32         `json:"count"`
-	VSize        int32         `json:"vsize"`
-	TotalFee     int64         `json:"total_fee"`
-	FeeHistogram []interface{} `json:"fee_histogram"`
+	Count        int32        `json:"count"`
+	VSize        int32        `json:"vsize"`
+	TotalFee     Satoshi      `json:"total_fee"`
+	FeeHistogram FeeHistogram `json:"fee_histogram"`
 }
 
 type MemPoolOverviewData struct {
-	ID    TxID  `json:"txid"`
-	Fee   int32 `json:"fee"`
-	VSize int32 `json:"vsize"`
-	Value int64 `json:"value"`
+	ID    TxID    `json:"txid"`
+	Fee   Satoshi `json:"fee"`
+	VSize int32   `json:"vsize"`
+	Value Satoshi `json:"value"`
 }
 
+// FeeEstimates maps a confirmation target in blocks to a fee rate in sat/vB.
 type FeeEstimates map[string]float64
+
+// BlockHeaderHex is a serialized 80-byte block header, hex-encoded.
+type BlockHeaderHex string
+
+const blockHeaderHexLen = 160
+
+type ElectrumBalance struct {
+	Confirmed   Satoshi `json:"confirmed"`
+	Unconfirmed Satoshi `json:"unconfirmed"`
+}
+
+// ElectrumHistoryItem is an entry of blockchain.scripthash.get_history. Height
+// is 0 for unconfirmed transactions and -1 for ones with unconfirmed inputs;
+// Fee is only set for those.
+type ElectrumHistoryItem struct {
+	TxHash TxID        `json:"tx_hash"`
+	Height BlockHeight `json:"height"`
+	Fee    Satoshi     `json:"fee,omitempty"`
+}
+
+type HeaderNotification struct {
+	Height BlockHeight    `json:"height"`
+	Hex    BlockHeaderHex `json:"hex"`
+}
+
+// ScriptHashStatus is a scripthash status notification. Status is empty
+// when the scripthash has no history.
+type ScriptHashStatus struct {
+	ScriptHash ScriptHash
+	Status     string
+}
@@ -0,0 +1,550 @@
+package pkg
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type PaymentEventType int
+
+const (
+	// PaymentSeen: an unconfirmed transaction pays the watched target.
+	PaymentSeen PaymentEventType = iota
+	// PaymentConfirmed: the payment got its first confirmation.
+	PaymentConfirmed
+	// PaymentConfirmationsReached: the payment reached the required
+	// confirmations and is no longer tracked.
+	PaymentConfirmationsReached
+	// PaymentDropped: a payment below the required confirmations vanished,
+	// e.g. because it was evicted, replaced, or reorged out and not returned
+	// to the mempool.
+	PaymentDropped
+	// OutputSpent: an output of the watched target was spent.
+	OutputSpent
+	// PaymentUnconfirmed: a reorg returned a confirmed payment to the
+	// mempool; PaymentConfirmed fires again once it is mined.
+	PaymentUnconfirmed
+)
+
+func (t PaymentEventType) String() string {
+	switch t {
+	case PaymentSeen:
+		return "PaymentSeen"
+	case PaymentConfirmed:
+		return "PaymentConfirmed"
+	case PaymentConfirmationsReached:
+		return "PaymentConfirmationsReached"
+	case PaymentDropped:
+		return "PaymentDropped"
+	case OutputSpent:
+		return "OutputSpent"
+	case PaymentUnconfirmed:
+		return "PaymentUnconfirmed"
+	}
+	return fmt.Sprintf("PaymentEventType(%d)", int(t))
+}
+
+// PaymentEvent concerns the output TxID:VOut of the watched Address or
+// ScriptHash, whichever was added. For OutputSpent, SpendingTxID spends it.
+type PaymentEvent struct {
+	Type          PaymentEventType
+	Address       Address
+	ScriptHash    ScriptHash
+	TxID          TxID
+	VOut          int32
+	Value         Satoshi
+	BlockHeight   BlockHeight
+	Confirmations int32
+	SpendingTxID  TxID
+}
+
+type WatcherOption func(*watcherConfig)
+
+type watcherConfig struct {
+	pollInterval  time.Duration
+	maxRetryDelay time.Duration
+	confirmations int32
+	concurrency   int
+	eventBuffer   int
+	onPollError   func(error)
+}
+
+func WatchPollInterval(interval time.Duration) WatcherOption {
+	return func(c *watcherConfig) { c.pollInterval = interval }
+}
+
+// WatchMaxRetryDelay caps the backoff of Run after failed polls, which
+// starts at the poll interval and doubles with every consecutive failure. A
+// cap below the poll interval disables the backoff.
+func WatchMaxRetryDelay(delay time.Duration) WatcherOption {
+	return func(c *watcherConfig) { c.maxRetryDelay = delay }
+}
+
+// OnPollError is called with every error Run retries.
+func OnPollError(fn func(error)) WatcherOption {
+	return func(c *watcherConfig) { c.onPollError = fn }
+}
+
+// RequiredConfirmations sets when PaymentConfirmationsReached fires.
+func RequiredConfirmations(confirmations int32) WatcherOption {
+	return func(c *watcherConfig) { c.confirmations = confirmations }
+}
+
+// WatchConcurrency bounds how many targets are polled at once.
+func WatchConcurrency(concurrency int) WatcherOption {
+	return func(c *watcherConfig) { c.concurrency = concurrency }
+}
+
+func WatchEventBuffer(size int) WatcherOption {
+	return func(c *watcherConfig) { c.eventBuffer = size }
+}
+
+// Watcher polls a dynamic set of addresses and scripthashes and emits
+// PaymentEvents. Each poll only fetches the stats of every target; history
+// is fetched only for targets whose chain or mempool stats changed, walking
+// back until the first transaction that already has the required
+// confirmations.
+//
+// Targets are baselined on their first poll: existing history that already
+// has the required confirmations produces no events. Reorgs are reported for
+// payments below the required confirmations only; once a payment has reached
+// them it is no longer tracked.
+type Watcher struct {
+	api    Esplora
+	config watcherConfig
+	events chan PaymentEvent
+
+	mu      sync.Mutex
+	targets map[watchTarget]*watchState
+}
+
+func NewWatcher(api Esplora, opts ...WatcherOption) *Watcher {
+	config := watcherConfig{pollInterval: 30 * time.Second, maxRetryDelay: 5 * time.Minute, confirmations: 6, concurrency: 8, eventBuffer: 64}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.confirmations < 1 {
+		config.confirmations = 1
+	}
+	if config.concurrency < 1 {
+		config.concurrency = 1
+	}
+	return &Watcher{
+		api:     api,
+		config:  config,
+		events:  make(chan PaymentEvent, config.eventBuffer),
+		targets: make(map[watchTarget]*watchState),
+	}
+}
+
+func (w *Watcher) AddAddress(address Address) {
+	w.add(watchTarget{address: address})
+}
+
+func (w *Watcher) RemoveAddress(address Address) {
+	w.remove(watchTarget{address: address})
+}
+
+func (w *Watcher) AddScriptHash(hash ScriptHash) {
+	w.add(watchTarget{scriptHash: hash})
+}
+
+func (w *Watcher) RemoveScriptHash(hash ScriptHash) {
+	w.remove(watchTarget{scriptHash: hash})
+}
+
+// Events is closed when Run returns.
+func (w *Watcher) Events() <-chan PaymentEvent {
+	return w.events
+}
+
+// Run polls every poll interval until ctx is done. Failed polls are retried
+// with backoff; targets that were polled successfully keep their progress.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	failures := 0
+	for {
+		delay := w.config.pollInterval
+		if err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("request canceled: %w", ctx.Err())
+			}
+			if w.config.onPollError != nil {
+				w.config.onPollError(err)
+			}
+			ceiling := w.config.maxRetryDelay
+			if ceiling < delay {
+				ceiling = delay
+			}
+			for i := 0; i < failures && delay < ceiling; i++ {
+				delay *= 2
+			}
+			if delay > ceiling {
+				delay = ceiling
+			}
+			failures++
+		} else {
+			failures = 0
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("request canceled: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll checks every target once. It returns the first error, after all
+// targets were attempted.
+func (w *Watcher) Poll(ctx context.Context) error {
+	tip, err := w.api.GetLastBlockHeightCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	states := make([]*watchState, 0, len(w.targets))
+	for _, state := range w.targets {
+		states = append(states, state)
+	}
+	w.mu.Unlock()
+
+	errs := make([]error, len(states))
+	sem := make(chan struct{}, w.config.concurrency)
+	var wg sync.WaitGroup
+	for i, state := range states {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, state *watchState) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			errs[i] = w.poll(ctx, state, tip)
+		}(i, state)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) add(target watchTarget) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.targets[target]; !ok {
+		w.targets[target] = &watchState{
+			target:   target,
+			payments: make(map[string]*trackedPayment),
+			spent:    make(map[string]TxID),
+			settled:  make(map[TxID]BlockHeight),
+		}
+	}
+}
+
+func (w *Watcher) remove(target watchTarget) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if state, ok := w.targets[target]; ok {
+		state.removed = true
+		delete(w.targets, target)
+	}
+}
+
+type watchTarget struct {
+	address    Address
+	scriptHash ScriptHash
+}
+
+func (t watchTarget) matches(out *TransactionOut) bool {
+	if t.address != "" {
+		return out.ScriptPubKeyAddress != "" && Address(out.ScriptPubKeyAddress) == t.address
+	}
+	script, err := hex.DecodeString(out.ScriptPubKey)
+	return err == nil && ScriptHashFromScript(script) == t.scriptHash
+}
+
+func (t watchTarget) stats(ctx context.Context, api AddressAPI) (ChainStats, MemStats, error) {
+	if t.address != "" {
+		info, err := api.GetAddressInfoCtx(ctx, t.address)
+		if err != nil {
+			return ChainStats{}, MemStats{}, err
+		}
+		return info.ChainStats, info.MemStats, nil
+	}
+	info, err := api.GetScriptHashInfoCtx(ctx, t.scriptHash)
+	if err != nil {
+		return ChainStats{}, MemStats{}, err
+	}
+	return info.ChainStats, info.MemStats, nil
+}
+
+func (t watchTarget) history(ctx context.Context, api AddressAPI, opts ...HistoryOption) *TxIterator {
+	if t.address != "" {
+		return NewAddressHistory(ctx, api, t.address, opts...)
+	}
+	return NewScriptHashHistory(ctx, api, t.scriptHash, opts...)
+}
+
+type watchState struct {
+	mu          sync.Mutex
+	target      watchTarget
+	removed     bool
+	initialized bool
+	chainStats  ChainStats
+	memStats    MemStats
+	// payments are outputs to the target below the required confirmations,
+	// keyed by outpoint.
+	payments map[string]*trackedPayment
+	// spent maps reported spent outpoints to the spending transaction until
+	// that transaction is settled.
+	spent map[string]TxID
+	// settled transactions have the required confirmations, by height.
+	// History walks stop at the first one, so only those at the highest
+	// height are kept.
+	settled map[TxID]BlockHeight
+}
+
+type trackedPayment struct {
+	txID      TxID
+	vOut      int32
+	value     Satoshi
+	height    BlockHeight
+	confirmed bool
+}
+
+func (w *Watcher) poll(ctx context.Context, state *watchState, tip BlockHeight) error {
+	chainStats, memStats, err := state.target.stats(ctx, w.api)
+	if err != nil {
+		return err
+	}
+	if state.initialized && chainStats == state.chainStats && memStats == state.memStats {
+		err := w.checkConfirmations(ctx, state, tip)
+		state.pruneSettled()
+		return err
+	}
+
+	// The tip was read before the stats, so a transaction may have been
+	// mined above it since. Raising the tip keeps such a transaction from
+	// being taken for unconfirmed while the stats that include it are saved.
+	raiseTip := func(tx *Transaction) {
+		if tx.Status.Confirmed && tx.Status.BlockHeight > tip {
+			tip = tx.Status.BlockHeight
+		}
+	}
+
+	it := state.target.history(ctx, w.api, IncludeMempool(), StopWhen(func(tx *Transaction) bool {
+		raiseTip(tx)
+		if _, ok := state.settled[tx.ID]; ok {
+			return true
+		}
+		// The baseline walk stops at the newest settled transaction, which
+		// is where every later walk stops too.
+		if !state.initialized && confirmationsAt(tx.Status, tip) >= w.config.confirmations {
+			state.settled[tx.ID] = tx.Status.BlockHeight
+			return true
+		}
+		return false
+	}))
+	seen := make(map[TxID]bool)
+	for it.Next() {
+		seen[it.Tx().ID] = true
+		if err := w.processTx(ctx, state, it.Tx(), tip); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	// Payments missing from the walk were dropped from the mempool or
+	// reorged out of the chain.
+	for key, payment := range state.payments {
+		if seen[payment.txID] {
+			continue
+		}
+		tx, err := w.api.GetTransactionCtx(ctx, payment.txID)
+		if errors.Is(err, ErrNotFound) {
+			delete(state.payments, key)
+			if err := w.emit(ctx, state, payment, PaymentDropped, tip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		raiseTip(tx)
+		if err := w.processTx(ctx, state, tx, tip); err != nil {
+			return err
+		}
+	}
+
+	state.chainStats, state.memStats, state.initialized = chainStats, memStats, true
+	state.pruneSettled()
+	return nil
+}
+
+func (w *Watcher) processTx(ctx context.Context, state *watchState, tx *Transaction, tip BlockHeight) error {
+	confirmations := confirmationsAt(tx.Status, tip)
+	settled := confirmations >= w.config.confirmations
+
+	for vOut, out := range tx.VOut {
+		if !state.target.matches(out) {
+			continue
+		}
+		key := outPointKey(tx.ID, int64(vOut))
+		payment, ok := state.payments[key]
+		if !ok {
+			payment = &trackedPayment{txID: tx.ID, vOut: int32(vOut), value: out.Value}
+			state.payments[key] = payment
+			if confirmations == 0 {
+				if err := w.emit(ctx, state, payment, PaymentSeen, tip); err != nil {
+					return err
+				}
+			}
+		}
+
+		payment.height = tx.Status.BlockHeight
+		if confirmations == 0 {
+			unconfirmed := payment.confirmed
+			payment.confirmed, payment.height = false, 0
+			if unconfirmed {
+				if err := w.emit(ctx, state, payment, PaymentUnconfirmed, tip); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !payment.confirmed {
+			payment.confirmed = true
+			if err := w.emit(ctx, state, payment, PaymentConfirmed, tip); err != nil {
+				return err
+			}
+		}
+		if settled {
+			delete(state.payments, key)
+			if err := w.emit(ctx, state, payment, PaymentConfirmationsReached, tip); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, in := range tx.VIn {
+		if !state.target.matches(&in.PrevOut) {
+			continue
+		}
+		key := outPointKey(in.ID, in.VOut)
+		if _, ok := state.spent[key]; ok {
+			continue
+		}
+		state.spent[key] = tx.ID
+		event := PaymentEvent{
+			Type:          OutputSpent,
+			TxID:          in.ID,
+			VOut:          int32(in.VOut),
+			Value:         in.PrevOut.Value,
+			BlockHeight:   tx.Status.BlockHeight,
+			Confirmations: confirmations,
+			SpendingTxID:  tx.ID,
+		}
+		if err := w.send(ctx, state, event); err != nil {
+			return err
+		}
+	}
+
+	if settled {
+		state.settled[tx.ID] = tx.Status.BlockHeight
+		for key, spendingTxID := range state.spent {
+			if spendingTxID == tx.ID {
+				delete(state.spent, key)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConfirmations advances confirmed payments as the tip moves without
+// fetching any history.
+func (w *Watcher) checkConfirmations(ctx context.Context, state *watchState, tip BlockHeight) error {
+	for key, payment := range state.payments {
+		if !payment.confirmed || tip-payment.height+1 < BlockHeight(w.config.confirmations) {
+			continue
+		}
+		delete(state.payments, key)
+		state.settled[payment.txID] = payment.height
+		if err := w.emit(ctx, state, payment, PaymentConfirmationsReached, tip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneSettled keeps the settled transactions at the highest height, the
+// only ones a history walk can reach.
+func (s *watchState) pruneSettled() {
+	var top BlockHeight
+	for _, height := range s.settled {
+		if height > top {
+			top = height
+		}
+	}
+	for txID, height := range s.settled {
+		if height < top {
+			delete(s.settled, txID)
+		}
+	}
+}
+
+func (w *Watcher) emit(ctx context.Context, state *watchState, payment *trackedPayment, eventType PaymentEventType, tip BlockHeight) error {
+	event := PaymentEvent{
+		Type:        eventType,
+		TxID:        payment.txID,
+		VOut:        payment.vOut,
+		Value:       payment.value,
+		BlockHeight: payment.height,
+	}
+	if payment.confirmed {
+		event.Confirmations = int32(tip - payment.height + 1)
+	}
+	return w.send(ctx, state, event)
+}
+
+func (w *Watcher) send(ctx context.Context, state *watchState, event PaymentEvent) error {
+	w.mu.Lock()
+	removed := state.removed
+	w.mu.Unlock()
+	if removed {
+		return nil
+	}
+
+	event.Address, event.ScriptHash = state.target.address, state.target.scriptHash
+	select {
+	case w.events <- event:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("request canceled: %w", ctx.Err())
+	}
+}
+
+func confirmationsAt(status TransactionStatus, tip BlockHeight) int32 {
+	if !status.Confirmed || status.BlockHeight > tip {
+		return 0
+	}
+	return int32(tip - status.BlockHeight + 1)
+}
+
+func outPointKey(txID TxID, vOut int64) string {
+	return fmt.Sprintf("%s:%d", txID, vOut)
+}
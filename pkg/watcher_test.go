@@ -0,0 +1,257 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"testing"
+	"time"
+)
+
+const deposit = pkg.Address("bc1qdeposit")
+
+// countingFake counts history requests to check that unchanged targets are
+// not re-fetched and that walks stop early.
+type countingFake struct {
+	*esploratest.Fake
+	historyCalls int
+	chainCalls   int
+}
+
+func (f *countingFake) GetAddressTransactionsInMemPoolCtx(ctx context.Context, address pkg.Address) ([]*pkg.Transaction, error) {
+	f.historyCalls++
+	return f.Fake.GetAddressTransactionsInMemPoolCtx(ctx, address)
+}
+
+func (f *countingFake) GetAddressTransactionsLatestCtx(ctx context.Context, address pkg.Address, lastTxID pkg.TxID) ([]*pkg.Transaction, error) {
+	f.chainCalls++
+	return f.Fake.GetAddressTransactionsLatestCtx(ctx, address, lastTxID)
+}
+
+// racingFake runs mineDuringTip right after answering the next tip request,
+// as if a block arrived between the tip and the stats requests of a poll.
+type racingFake struct {
+	*esploratest.Fake
+	mineDuringTip func()
+}
+
+func (f *racingFake) GetLastBlockHeightCtx(ctx context.Context) (pkg.BlockHeight, error) {
+	tip, err := f.Fake.GetLastBlockHeightCtx(ctx)
+	if f.mineDuringTip != nil {
+		f.mineDuringTip()
+		f.mineDuringTip = nil
+	}
+	return tip, err
+}
+
+func txID(label string) pkg.TxID {
+	return pkg.TxID(fmt.Sprintf("%064s", label))
+}
+
+func paymentTx(label string, value pkg.Satoshi) *pkg.Transaction {
+	return &pkg.Transaction{
+		ID:   txID(label),
+		VOut: []*pkg.TransactionOut{{ScriptPubKey: "0014aa", ScriptPubKeyAddress: string(deposit), Value: value}},
+	}
+}
+
+func mine(fake *esploratest.Fake, height int, txs ...*pkg.Transaction) {
+	fake.AddBlock(&pkg.Block{ID: blockHash("a", height), Height: pkg.BlockHeight(height), PreviousBlockHash: blockHash("a", height-1)}, txs...)
+}
+
+func drainPayments(watcher *pkg.Watcher) []string {
+	events := make([]string, 0)
+	for {
+		select {
+		case event := <-watcher.Events():
+			described := fmt.Sprintf("%s %s:%d", event.Type, event.TxID[60:], event.VOut)
+			if event.SpendingTxID != "" {
+				described += " by " + string(event.SpendingTxID[60:])
+			}
+			events = append(events, described)
+		default:
+			return events
+		}
+	}
+}
+
+func poll(t *testing.T, watcher *pkg.Watcher, expected ...string) {
+	t.Helper()
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	if got := drainPayments(watcher); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	fake := &countingFake{Fake: esploratest.New()}
+	old := paymentTx("old0", 1000)
+	mine(fake.Fake, 1, old)
+	mine(fake.Fake, 2)
+	mine(fake.Fake, 3)
+
+	watcher := pkg.NewWatcher(fake, pkg.RequiredConfirmations(3))
+	watcher.AddAddress(deposit)
+	poll(t, watcher)
+
+	payment := paymentTx("pay1", 5000)
+	fake.AddTransaction(payment)
+	poll(t, watcher, "PaymentSeen pay1:0")
+
+	calls := fake.historyCalls
+	poll(t, watcher)
+	if fake.historyCalls != calls {
+		t.Errorf("history fetched although stats did not change")
+	}
+
+	mine(fake.Fake, 4, payment)
+	poll(t, watcher, "PaymentConfirmed pay1:0")
+
+	mine(fake.Fake, 5)
+	mine(fake.Fake, 6)
+	poll(t, watcher, "PaymentConfirmationsReached pay1:0")
+
+	spend := &pkg.Transaction{
+		ID:  txID("spd2"),
+		VIn: []*pkg.TransactionIn{{ID: payment.ID, VOut: 0, PrevOut: *payment.VOut[0]}},
+	}
+	fake.AddTransaction(spend)
+	poll(t, watcher, "OutputSpent pay1:0 by spd2")
+
+	replaced := paymentTx("rbf3", 7000)
+	fake.AddTransaction(replaced)
+	poll(t, watcher, "PaymentSeen rbf3:0")
+	fake.RemoveTransaction(replaced.ID)
+	poll(t, watcher, "PaymentDropped rbf3:0")
+
+	watcher.RemoveAddress(deposit)
+	fake.AddTransaction(paymentTx("gone", 1))
+	poll(t, watcher)
+}
+
+func TestWatcher_ScriptHash(t *testing.T) {
+	fake := esploratest.New()
+	mine(fake, 1)
+
+	watcher := pkg.NewWatcher(fake, pkg.RequiredConfirmations(1))
+	// sha256(0x0014aa), reversed
+	watcher.AddScriptHash("940264f5762eac1844db3534353da346eec79b1e2cb6a073854bced3948e0284")
+	poll(t, watcher)
+
+	mine(fake, 2, paymentTx("pay1", 5000))
+	poll(t, watcher, "PaymentConfirmed pay1:0", "PaymentConfirmationsReached pay1:0")
+}
+
+func TestWatcher_Baseline(t *testing.T) {
+	fake := &countingFake{Fake: esploratest.New()}
+	for height := 1; height <= 60; height++ {
+		mine(fake.Fake, height, paymentTx(fmt.Sprintf("o%03d", height), 1000))
+	}
+
+	watcher := pkg.NewWatcher(fake, pkg.RequiredConfirmations(3))
+	watcher.AddAddress(deposit)
+	poll(t, watcher, "PaymentConfirmed o060:0", "PaymentConfirmed o059:0")
+	if fake.chainCalls != 1 {
+		t.Errorf("expected the baseline to stop on the first page, got %d requests", fake.chainCalls)
+	}
+
+	mine(fake.Fake, 61, paymentTx("p061", 1000))
+	fake.chainCalls = 0
+	poll(t, watcher, "PaymentConfirmed p061:0", "PaymentConfirmationsReached o059:0")
+	if fake.chainCalls != 1 {
+		t.Errorf("expected the walk to stop on the first page, got %d requests", fake.chainCalls)
+	}
+}
+
+func TestWatcher_Reorg(t *testing.T) {
+	fake := esploratest.New()
+	mine(fake, 1)
+
+	watcher := pkg.NewWatcher(fake, pkg.RequiredConfirmations(3))
+	watcher.AddAddress(deposit)
+	poll(t, watcher)
+
+	payment := paymentTx("pay1", 5000)
+	mine(fake, 2, payment)
+	poll(t, watcher, "PaymentConfirmed pay1:0")
+
+	fake.AddBlock(&pkg.Block{ID: blockHash("b", 2), Height: 2, PreviousBlockHash: blockHash("a", 1)})
+	poll(t, watcher, "PaymentUnconfirmed pay1:0")
+
+	fake.AddBlock(&pkg.Block{ID: blockHash("b", 3), Height: 3, PreviousBlockHash: blockHash("b", 2)}, payment)
+	poll(t, watcher, "PaymentConfirmed pay1:0")
+
+	fake.AddBlock(&pkg.Block{ID: blockHash("c", 3), Height: 3, PreviousBlockHash: blockHash("b", 2)})
+	fake.RemoveTransaction(payment.ID)
+	poll(t, watcher, "PaymentDropped pay1:0")
+}
+
+func TestWatcher_MinedDuringPoll(t *testing.T) {
+	fake := &racingFake{Fake: esploratest.New()}
+	mine(fake.Fake, 1)
+
+	watcher := pkg.NewWatcher(fake, pkg.RequiredConfirmations(3))
+	watcher.AddAddress(deposit)
+	poll(t, watcher)
+
+	payment := paymentTx("pay1", 5000)
+	fake.AddTransaction(payment)
+	poll(t, watcher, "PaymentSeen pay1:0")
+
+	fake.mineDuringTip = func() { mine(fake.Fake, 2, payment) }
+	poll(t, watcher, "PaymentConfirmed pay1:0")
+
+	mine(fake.Fake, 3)
+	mine(fake.Fake, 4)
+	poll(t, watcher, "PaymentConfirmationsReached pay1:0")
+}
+
+func TestWatcher_RunRetries(t *testing.T) {
+	fake := esploratest.New()
+	mine(fake, 1)
+	fake.SetError(pkg.ErrUnavailable)
+
+	pollErrors := make(chan error, 16)
+	watcher := pkg.NewWatcher(fake, pkg.WatchPollInterval(time.Millisecond), pkg.WatchMaxRetryDelay(4*time.Millisecond),
+		pkg.OnPollError(func(err error) {
+			select {
+			case pollErrors <- err:
+			default:
+			}
+		}))
+	watcher.AddAddress(deposit)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx) }()
+
+	select {
+	case err := <-pollErrors:
+		if !errors.Is(err, pkg.ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a poll error")
+	}
+	fake.SetError(nil)
+	fake.AddTransaction(paymentTx("pay1", 5000))
+	select {
+	case event := <-watcher.Events():
+		if event.Type != pkg.PaymentSeen || event.TxID != txID("pay1") {
+			t.Fatalf("unexpected event %s %s", event.Type, event.TxID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-watcher.Events(); ok {
+		t.Errorf("events channel not closed")
+	}
+}
@@ -0,0 +1,229 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"io"
+)
+
+var ErrInvalidBlock = errors.New("invalid block")
+
+const (
+	blockHeaderLen = 80
+	// maxBlockSize is the largest serialized block consensus allows; it
+	// bounds every length read from a block stream.
+	maxBlockSize = 4000000
+)
+
+// BlockReader parses a serialized block one transaction at a time, so that
+// only the current transaction and the 32-byte txids and wtxids are held in
+// memory. Once the last transaction has been read the txids are checked
+// against the header's merkle root and, as in BIP141, the wtxids against the
+// coinbase's witness commitment.
+type BlockReader struct {
+	src    *bufio.Reader
+	header *pkg.BlockHeader
+	count  int
+	read   int
+	size   int
+	txIDs  [][]byte
+	wtxIDs [][]byte
+	err    error
+
+	// witness is set once any transaction carries witness data.
+	witness bool
+	// commitment and reserved are the coinbase's witness commitment and
+	// witness reserved value, nil when absent.
+	commitment []byte
+	reserved   []byte
+}
+
+// NewBlockReader reads the header and transaction count from r.
+func NewBlockReader(r io.Reader) (*BlockReader, error) {
+	b := &BlockReader{src: bufio.NewReader(r)}
+	raw := &reader{src: b.src, limit: maxBlockSize}
+	headerRaw := raw.bytes(blockHeaderLen)
+	b.count = raw.count(60)
+	if raw.err != nil {
+		return nil, blockError(raw.err)
+	}
+	header, err := pkg.DecodeBlockHeader(headerRaw)
+	if err != nil {
+		return nil, err
+	}
+	if b.count == 0 {
+		return nil, fmt.Errorf("%w: no transactions", ErrInvalidBlock)
+	}
+	b.header = header
+	b.size = len(raw.raw)
+	b.txIDs = make([][]byte, 0, b.count)
+	b.wtxIDs = make([][]byte, 0, b.count)
+	return b, nil
+}
+
+func (b *BlockReader) Header() *pkg.BlockHeader {
+	return b.header
+}
+
+func (b *BlockReader) TxCount() int {
+	return b.count
+}
+
+// Next returns the next transaction. After the last one it returns io.EOF
+// if the stream ended there and the transactions hash to the header's
+// merkle root and witness commitment, and an error wrapping ErrInvalidBlock
+// otherwise.
+func (b *BlockReader) Next() (*RawTx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.read == b.count {
+		b.err = b.finish()
+		return nil, b.err
+	}
+
+	r := &reader{src: b.src, limit: maxBlockSize - b.size}
+	tx, err := readTx(r)
+	if err != nil {
+		b.err = blockError(fmt.Errorf("tx %d: %w", b.read, err))
+		return nil, b.err
+	}
+	if (b.read == 0) != tx.Inputs[0].IsCoinbase() || (b.read == 0 && len(tx.Inputs) != 1) {
+		b.err = fmt.Errorf("%w: tx %d: coinbase must be the first and only the first transaction", ErrInvalidBlock, b.read)
+		return nil, b.err
+	}
+	txID, _ := hex.DecodeString(string(tx.TxID))
+	b.txIDs = append(b.txIDs, reverseBytes(txID))
+	if b.read == 0 {
+		// The coinbase's wtxid is committed to as all zeros.
+		b.wtxIDs = append(b.wtxIDs, make([]byte, 32))
+		b.commitment = witnessCommitment(tx)
+		if witness := tx.Inputs[0].Witness; len(witness) == 1 && len(witness[0]) == 32 {
+			b.reserved = witness[0]
+		}
+	} else {
+		wtxID, _ := hex.DecodeString(string(tx.WTxID))
+		b.wtxIDs = append(b.wtxIDs, reverseBytes(wtxID))
+	}
+	b.witness = b.witness || tx.HasWitness()
+	b.size += len(tx.Raw)
+	b.read++
+	return tx, nil
+}
+
+func (b *BlockReader) finish() error {
+	if _, err := b.src.ReadByte(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: data after the last transaction", ErrInvalidBlock)
+	}
+
+	root, mutated := merkleRoot(b.txIDs)
+	if mutated {
+		return fmt.Errorf("%w: duplicate transactions in the merkle tree", ErrInvalidBlock)
+	}
+	if computed := hex.EncodeToString(reverseBytes(root)); computed != b.header.MerkleRoot {
+		return fmt.Errorf("%w: merkle root %s, header commits to %s", ErrInvalidBlock, computed, b.header.MerkleRoot)
+	}
+
+	if b.commitment == nil {
+		if b.witness {
+			return fmt.Errorf("%w: witness data without a witness commitment", ErrInvalidBlock)
+		}
+		return io.EOF
+	}
+	if b.reserved == nil {
+		return fmt.Errorf("%w: coinbase witness is not a single 32-byte reserved value", ErrInvalidBlock)
+	}
+	witnessRoot, _ := merkleRoot(b.wtxIDs)
+	if computed := doubleSHA256(append(witnessRoot, b.reserved...)); !bytes.Equal(computed, b.commitment) {
+		return fmt.Errorf("%w: witness commitment %x, coinbase commits to %x", ErrInvalidBlock, computed, b.commitment)
+	}
+	return io.EOF
+}
+
+// witnessCommitmentHeader starts a BIP141 witness commitment output script:
+// OP_RETURN, a 36-byte push and the 0xaa21a9ed tag.
+var witnessCommitmentHeader = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}
+
+// witnessCommitment returns the commitment of the last coinbase output
+// carrying one, or nil.
+func witnessCommitment(coinbase *RawTx) []byte {
+	for i := len(coinbase.Outputs) - 1; i >= 0; i-- {
+		script := coinbase.Outputs[i].ScriptPubKey
+		if len(script) >= 38 && bytes.HasPrefix(script, witnessCommitmentHeader) {
+			return script[6:38]
+		}
+	}
+	return nil
+}
+
+// blockError reports a truncated or corrupt stream as an invalid block,
+// leaving I/O errors of the underlying reader as they are.
+func blockError(err error) error {
+	if errors.Is(err, ErrInvalidTransaction) {
+		return fmt.Errorf("%w: %s", ErrInvalidBlock, err.Error())
+	}
+	return err
+}
+
+// merkleRoot computes the root of hashes in internal byte order. mutated
+// reports a level with two identical trailing hashes, which lets a block
+// with duplicated transactions share the root of a valid one
+// (CVE-2012-2459).
+func merkleRoot(hashes [][]byte) ([]byte, bool) {
+	level := hashes
+	mutated := false
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+				mutated = mutated || bytes.Equal(level[i], right)
+			}
+			next = append(next, doubleSHA256(append(append([]byte(nil), level[i]...), right...)))
+		}
+		level = next
+	}
+	return level[0], mutated
+}
+
+// ReadBlock downloads the block hash through api and calls fn with each of
+// its transactions. The header is checked to hash to hash and, at the end,
+// the transactions against its merkle root and witness commitment; a block
+// failing those checks may already have been partly passed to fn. An error
+// from fn stops the download and is returned as is.
+func ReadBlock(ctx context.Context, api pkg.BlockAPI, hash pkg.BlockHash, fn func(tx *RawTx) error) (*pkg.BlockHeader, error) {
+	body, err := api.GetRawBlockCtx(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	block, err := NewBlockReader(body)
+	if err != nil {
+		return nil, err
+	}
+	if block.Header().Hash() != hash {
+		return nil, fmt.Errorf("%w: requested %s, got %s", pkg.ErrHeaderHashMismatch, hash, block.Header().Hash())
+	}
+	for {
+		tx, err := block.Next()
+		if err == io.EOF {
+			return block.Header(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(tx); err != nil {
+			return nil, err
+		}
+	}
+}
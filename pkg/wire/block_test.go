@@ -0,0 +1,235 @@
+package wire_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"github.com/panda-next-team/electrs-client/pkg/wire"
+	"io"
+	"strings"
+	"testing"
+)
+
+const (
+	genesisHeader   = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c"
+	genesisCoinbase = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+)
+
+// rawBlock decodes the concatenated parts, by default the genesis block.
+func rawBlock(t *testing.T, parts ...string) []byte {
+	if len(parts) == 0 {
+		parts = []string{genesisHeader, "01", genesisCoinbase}
+	}
+	raw, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return raw
+}
+
+func TestBlockReader(t *testing.T) {
+	block, err := wire.NewBlockReader(bytes.NewReader(rawBlock(t)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if block.Header().Hash() != "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f" || block.TxCount() != 1 {
+		t.Errorf("unexpected header %+v", block.Header())
+	}
+
+	tx, err := block.Next()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tx.TxID != "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b" || !tx.Inputs[0].IsCoinbase() || tx.Outputs[0].Value != 5000000000 {
+		t.Errorf("unexpected coinbase %+v", tx)
+	}
+	if _, err := block.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if _, err := block.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF again, got %v", err)
+	}
+}
+
+func TestBlockReader_Invalid(t *testing.T) {
+	valid := hex.EncodeToString(rawBlock(t))
+	cases := map[string][]byte{
+		"truncated":     rawBlock(t, valid[:len(valid)-2]),
+		"trailing data": rawBlock(t, valid, "00"),
+		"merkle root":   rawBlock(t, genesisHeader[:72]+"00"+genesisHeader[74:], "01", genesisCoinbase),
+		"two txs":       rawBlock(t, genesisHeader, "02", genesisCoinbase, string(block170Tx)),
+		"no coinbase":   rawBlock(t, genesisHeader, "01", string(block170Tx)),
+		"two coinbases": rawBlock(t, genesisHeader, "02", genesisCoinbase, genesisCoinbase),
+	}
+	for name, raw := range cases {
+		block, err := wire.NewBlockReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		for err == nil {
+			_, err = block.Next()
+		}
+		if !errors.Is(err, wire.ErrInvalidBlock) {
+			t.Errorf("%s: expected ErrInvalidBlock, got %v", name, err)
+		}
+	}
+
+	if _, err := wire.NewBlockReader(bytes.NewReader(rawBlock(t, genesisHeader, "00"))); !errors.Is(err, wire.ErrInvalidBlock) {
+		t.Errorf("expected ErrInvalidBlock for an empty block, got %v", err)
+	}
+	if _, err := wire.NewBlockReader(bytes.NewReader(rawBlock(t, genesisHeader, "fe00ffffff"))); !errors.Is(err, wire.ErrInvalidBlock) {
+		t.Errorf("expected ErrInvalidBlock for a huge count, got %v", err)
+	}
+}
+
+// hashPair and merkleRoot are the test's own merkle tree over hashes in
+// display order.
+func hashPair(left, right []byte) []byte {
+	first := sha256.Sum256(append(append([]byte(nil), left...), right...))
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func merkleRoot(t *testing.T, ids ...pkg.TxID) string {
+	level := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		hash, err := hex.DecodeString(string(id))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		level = append(level, reverse(hash))
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			next = append(next, hashPair(level[i], right))
+		}
+		level = next
+	}
+	return hex.EncodeToString(reverse(level[0]))
+}
+
+func reverse(b []byte) []byte {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+	return reversed
+}
+
+func decode(t *testing.T, txHex pkg.TxHex) *wire.RawTx {
+	tx, err := wire.DecodeTransaction(txHex)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return tx
+}
+
+// segwitCoinbase returns a coinbase with a zero witness reserved value and a
+// BIP141 commitment to the wtxids of txs.
+func segwitCoinbase(t *testing.T, txs ...pkg.TxHex) pkg.TxHex {
+	wtxIDs := []pkg.TxID{pkg.TxID(strings.Repeat("00", 32))}
+	for _, tx := range txs {
+		wtxIDs = append(wtxIDs, decode(t, tx).WTxID)
+	}
+	root, _ := hex.DecodeString(merkleRoot(t, wtxIDs...))
+	commitment := hashPair(reverse(root), make([]byte, 32))
+	return pkg.TxHex("01000000" + "0001" +
+		"01" + strings.Repeat("00", 32) + "ffffffff" + "025101" + "ffffffff" +
+		"02" + "00f2052a01000000" + "0151" + "0000000000000000" + "26" + "6a24aa21a9ed" + hex.EncodeToString(commitment) +
+		"01" + "20" + strings.Repeat("00", 32) +
+		"00000000")
+}
+
+// segwitBlock serializes txs under a header committing to them.
+func segwitBlock(t *testing.T, txs ...pkg.TxHex) (*pkg.Block, []byte) {
+	ids := make([]pkg.TxID, 0, len(txs))
+	parts := []string{"", fmt.Sprintf("%02x", len(txs))}
+	for _, tx := range txs {
+		ids = append(ids, decode(t, tx).TxID)
+		parts = append(parts, string(tx))
+	}
+	block := &pkg.Block{Height: 1, Version: 1, Timestamp: 1231006505, Bits: 0x207fffff, MerkleRoot: merkleRoot(t, ids...)}
+	header, err := pkg.NewBlockHeader(block)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	block.ID = header.Hash()
+	parts[0] = hex.EncodeToString(header.Raw)
+	return block, rawBlock(t, parts...)
+}
+
+func TestBlockReader_WitnessCommitment(t *testing.T) {
+	cases := map[string]struct {
+		txs   []pkg.TxHex
+		valid bool
+	}{
+		"committed":          {[]pkg.TxHex{segwitCoinbase(t, block170Tx, segwitTx), block170Tx, segwitTx}, true},
+		"no witness data":    {[]pkg.TxHex{genesisCoinbase, block170Tx}, true},
+		"wrong commitment":   {[]pkg.TxHex{segwitCoinbase(t, block170Tx, block170Tx), block170Tx, segwitTx}, false},
+		"missing commitment": {[]pkg.TxHex{genesisCoinbase, block170Tx, segwitTx}, false},
+	}
+	for name, c := range cases {
+		_, raw := segwitBlock(t, c.txs...)
+		block, err := wire.NewBlockReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		for err == nil {
+			_, err = block.Next()
+		}
+		if c.valid && err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", name, err)
+		}
+		if !c.valid && !errors.Is(err, wire.ErrInvalidBlock) {
+			t.Errorf("%s: expected ErrInvalidBlock, got %v", name, err)
+		}
+	}
+}
+
+func TestReadBlock(t *testing.T) {
+	txs := []pkg.TxHex{segwitCoinbase(t, block170Tx, segwitTx), block170Tx, segwitTx}
+	block, _ := segwitBlock(t, txs...)
+
+	fake := esploratest.New()
+	seeded := make([]*pkg.Transaction, 0, len(txs))
+	ids := make([]pkg.TxID, 0, len(txs))
+	for _, tx := range txs {
+		id := decode(t, tx).TxID
+		ids = append(ids, id)
+		seeded = append(seeded, &pkg.Transaction{ID: id})
+		fake.SetTransactionHex(id, tx)
+	}
+	fake.AddBlock(block, seeded...)
+
+	read := make([]pkg.TxID, 0)
+	if _, err := wire.ReadBlock(context.Background(), fake, block.ID, func(tx *wire.RawTx) error {
+		read = append(read, tx.TxID)
+		return nil
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(read) != 3 || read[0] != ids[0] || read[1] != ids[1] || read[2] != ids[2] {
+		t.Errorf("unexpected transactions %v", read)
+	}
+
+	stop := errors.New("stop")
+	if _, err := wire.ReadBlock(context.Background(), fake, block.ID, func(tx *wire.RawTx) error { return stop }); err != stop {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+
+	// A server returning another block's data under this hash.
+	fake.AddBlock(&pkg.Block{ID: block.ID, Height: 1, Version: 2, Bits: 0x207fffff, MerkleRoot: block.MerkleRoot}, seeded...)
+	if _, err := wire.ReadBlock(context.Background(), fake, block.ID, func(tx *wire.RawTx) error { return nil }); !errors.Is(err, pkg.ErrHeaderHashMismatch) {
+		t.Errorf("expected ErrHeaderHashMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package wire
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"strings"
+)
+
+var ErrInconsistentTransaction = errors.New("server transaction does not match its serialization")
+
+// Compare checks the fields of the server's transaction JSON against the
+// decoded serialization and reports every difference in one error wrapping
+// ErrInconsistentTransaction. Fee and prevouts cannot be derived from the
+// serialization alone and are not compared.
+func Compare(raw *RawTx, tx *pkg.Transaction) error {
+	var diffs []string
+	diff := func(field string, server, local interface{}) {
+		if fmt.Sprint(server) != fmt.Sprint(local) {
+			diffs = append(diffs, fmt.Sprintf("%s: server %v, decoded %v", field, server, local))
+		}
+	}
+
+	diff("txid", tx.ID, raw.TxID)
+	diff("version", tx.Version, raw.Version)
+	diff("locktime", tx.LockTime, raw.LockTime)
+	diff("size", tx.Size, raw.Size)
+	diff("weight", tx.Weight, raw.Weight)
+
+	diff("vin count", len(tx.VIn), len(raw.Inputs))
+	for i := 0; i < len(tx.VIn) && i < len(raw.Inputs); i++ {
+		server, local := tx.VIn[i], raw.Inputs[i]
+		diff(fmt.Sprintf("vin %d txid", i), server.ID, local.PreviousTxID)
+		diff(fmt.Sprintf("vin %d vout", i), server.VOut, local.PreviousVOut)
+		diff(fmt.Sprintf("vin %d scriptsig", i), server.ScriptSig, hex.EncodeToString(local.ScriptSig))
+		diff(fmt.Sprintf("vin %d sequence", i), server.Sequence, local.Sequence)
+		diff(fmt.Sprintf("vin %d is_coinbase", i), server.IsCoinBase, local.IsCoinbase())
+		witness := make([]string, 0, len(local.Witness))
+		for _, item := range local.Witness {
+			witness = append(witness, hex.EncodeToString(item))
+		}
+		diff(fmt.Sprintf("vin %d witness", i), strings.Join(server.Witness, " "), strings.Join(witness, " "))
+	}
+
+	diff("vout count", len(tx.VOut), len(raw.Outputs))
+	for i := 0; i < len(tx.VOut) && i < len(raw.Outputs); i++ {
+		server, local := tx.VOut[i], raw.Outputs[i]
+		diff(fmt.Sprintf("vout %d value", i), server.Value, local.Value)
+		diff(fmt.Sprintf("vout %d scriptpubkey", i), server.ScriptPubKey, hex.EncodeToString(local.ScriptPubKey))
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInconsistentTransaction, strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// GetCheckedTransaction fetches both the JSON and the serialization of
+// txID and returns them once the serialization hashes to txID and agrees
+// with the JSON.
+func GetCheckedTransaction(ctx context.Context, api pkg.TxAPI, txID pkg.TxID) (*RawTx, *pkg.Transaction, error) {
+	txHex, err := api.GetTransactionHexCtx(ctx, txID)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := DecodeTransaction(txHex)
+	if err != nil {
+		return nil, nil, err
+	}
+	if raw.TxID != txID {
+		return nil, nil, fmt.Errorf("%w: requested %s, serialization hashes to %s", ErrInconsistentTransaction, txID, raw.TxID)
+	}
+
+	tx, err := api.GetTransactionCtx(ctx, txID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := Compare(raw, tx); err != nil {
+		return nil, nil, err
+	}
+	return raw, tx, nil
+}
@@ -0,0 +1,280 @@
+// Package wire decodes the bitcoin serialization of transactions returned
+// by GetTransactionHex. Elements (Liquid) transactions use a different
+// serialization and are not supported.
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"io"
+)
+
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+const (
+	witnessScaleFactor = 4
+	coinbaseVOut       = 0xffffffff
+)
+
+// RawTx is a transaction decoded from its serialization. TxID, WTxID and
+// the sizes are computed locally rather than taken from the server.
+type RawTx struct {
+	Version  int32
+	Inputs   []*TxIn
+	Outputs  []*TxOut
+	LockTime uint32
+
+	TxID  pkg.TxID
+	WTxID pkg.TxID
+	// Size is the serialized size including witness data, VSize the
+	// weight divided by four, rounded up.
+	Size   int32
+	VSize  int32
+	Weight int32
+	Raw    []byte
+}
+
+type TxIn struct {
+	PreviousTxID pkg.TxID
+	PreviousVOut uint32
+	ScriptSig    []byte
+	Sequence     uint32
+	Witness      [][]byte
+}
+
+type TxOut struct {
+	Value        pkg.Satoshi
+	ScriptPubKey []byte
+}
+
+func (in *TxIn) IsCoinbase() bool {
+	return in.PreviousVOut == coinbaseVOut && in.PreviousTxID == pkg.TxID(hex.EncodeToString(make([]byte, 32)))
+}
+
+// HasWitness reports whether the transaction was serialized with the
+// segwit marker and flag.
+func (tx *RawTx) HasWitness() bool {
+	return tx.TxID != tx.WTxID
+}
+
+func DecodeTransaction(txHex pkg.TxHex) (*RawTx, error) {
+	raw, err := hex.DecodeString(string(txHex))
+	if err != nil {
+		return nil, &pkg.DecodeError{Path: "transaction", Body: string(txHex), Err: err}
+	}
+	tx, err := Decode(raw)
+	if err != nil {
+		return nil, &pkg.DecodeError{Path: "transaction", Body: string(txHex), Err: err}
+	}
+	return tx, nil
+}
+
+// Decode parses a serialized transaction, with or without witness data.
+// Errors wrap ErrInvalidTransaction.
+func Decode(raw []byte) (*RawTx, error) {
+	r := &reader{src: bytes.NewReader(raw), limit: len(raw)}
+	tx, err := readTx(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.raw) != len(raw) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidTransaction, len(raw)-len(r.raw))
+	}
+	return tx, nil
+}
+
+// readTx reads one transaction from r, keeping its serialization.
+func readTx(r *reader) (*RawTx, error) {
+	tx := &RawTx{Version: int32(r.uint32())}
+
+	// A zero input count is the segwit marker; legacy transactions always
+	// have inputs.
+	segwit := false
+	var inputs int
+	if marker := r.byte(); r.err == nil && marker == 0 {
+		if flag := r.byte(); r.err == nil && flag != 1 {
+			return nil, fmt.Errorf("%w: unknown segwit flag 0x%02x", ErrInvalidTransaction, flag)
+		}
+		segwit = true
+		inputs = r.count(41)
+	} else {
+		inputs = r.countFrom(marker, 41)
+	}
+
+	for i := 0; i < inputs && r.err == nil; i++ {
+		in := &TxIn{}
+		in.PreviousTxID = pkg.TxID(hex.EncodeToString(reverseBytes(r.bytes(32))))
+		in.PreviousVOut = r.uint32()
+		in.ScriptSig = r.varBytes()
+		in.Sequence = r.uint32()
+		tx.Inputs = append(tx.Inputs, in)
+	}
+	outputs := r.count(9)
+	for i := 0; i < outputs && r.err == nil; i++ {
+		out := &TxOut{Value: pkg.Satoshi(r.uint64())}
+		out.ScriptPubKey = r.varBytes()
+		tx.Outputs = append(tx.Outputs, out)
+	}
+
+	witnessStart := len(r.raw)
+	if segwit {
+		empty := true
+		for _, in := range tx.Inputs {
+			items := r.count(1)
+			for i := 0; i < items && r.err == nil; i++ {
+				in.Witness = append(in.Witness, r.varBytes())
+			}
+			empty = empty && items == 0
+		}
+		if r.err == nil && empty {
+			return nil, fmt.Errorf("%w: segwit marker without witness data", ErrInvalidTransaction)
+		}
+	}
+	witnessEnd := len(r.raw)
+	tx.LockTime = r.uint32()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(tx.Inputs) == 0 {
+		return nil, fmt.Errorf("%w: no inputs", ErrInvalidTransaction)
+	}
+
+	raw := r.raw
+	stripped := raw
+	if segwit {
+		stripped = make([]byte, 0, len(raw)-2-(witnessEnd-witnessStart))
+		stripped = append(stripped, raw[:4]...)
+		stripped = append(stripped, raw[6:witnessStart]...)
+		stripped = append(stripped, raw[witnessEnd:]...)
+	}
+	tx.Raw = raw
+	tx.TxID = pkg.TxID(hex.EncodeToString(reverseBytes(doubleSHA256(stripped))))
+	tx.WTxID = pkg.TxID(hex.EncodeToString(reverseBytes(doubleSHA256(raw))))
+	tx.Size = int32(len(raw))
+	tx.Weight = int32(len(stripped)*(witnessScaleFactor-1) + len(raw))
+	tx.VSize = (tx.Weight + witnessScaleFactor - 1) / witnessScaleFactor
+	return tx, nil
+}
+
+// reader reads little-endian fields from src, recording them in raw, and
+// keeps the first error, after which every read returns zero values. limit
+// bounds the bytes read so that a corrupt length fails before anything is
+// allocated for it.
+type reader struct {
+	src   io.Reader
+	raw   []byte
+	limit int
+	err   error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.limit-len(r.raw) {
+		r.err = fmt.Errorf("%w: %d bytes at byte %d exceed the size limit", ErrInvalidTransaction, n, len(r.raw))
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.src, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("%w: unexpected end at byte %d", ErrInvalidTransaction, len(r.raw))
+		}
+		r.err = err
+		return nil
+	}
+	r.raw = append(r.raw, b...)
+	return b
+}
+
+func (r *reader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// varIntFrom reads the rest of a CompactSize starting with prefix,
+// rejecting non-canonical encodings.
+func (r *reader) varIntFrom(prefix byte) uint64 {
+	var value, min uint64
+	switch prefix {
+	case 0xfd:
+		if b := r.bytes(2); b != nil {
+			value, min = uint64(binary.LittleEndian.Uint16(b)), 0xfd
+		}
+	case 0xfe:
+		value, min = uint64(r.uint32()), 0x10000
+	case 0xff:
+		value, min = r.uint64(), 0x100000000
+	default:
+		return uint64(prefix)
+	}
+	if r.err == nil && value < min {
+		r.err = fmt.Errorf("%w: non-canonical compact size at byte %d", ErrInvalidTransaction, len(r.raw))
+	}
+	return value
+}
+
+// count reads a number of items that each take at least size bytes.
+func (r *reader) count(size int) int {
+	prefix := r.byte()
+	if r.err != nil {
+		return 0
+	}
+	return r.countFrom(prefix, size)
+}
+
+func (r *reader) countFrom(prefix byte, size int) int {
+	n := r.varIntFrom(prefix)
+	if r.err == nil && n > uint64((r.limit-len(r.raw))/size) {
+		r.err = fmt.Errorf("%w: count %d exceeds the size limit at byte %d", ErrInvalidTransaction, n, len(r.raw))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) varBytes() []byte {
+	b := r.bytes(r.count(1))
+	if b == nil && r.err == nil {
+		return []byte{}
+	}
+	return b
+}
+
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package wire_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/panda-next-team/electrs-client/pkg"
+	"github.com/panda-next-team/electrs-client/pkg/esploratest"
+	"github.com/panda-next-team/electrs-client/pkg/wire"
+	"testing"
+)
+
+// The first bitcoin transaction to another person, in block 170.
+const block170Tx pkg.TxHex = "0100000001c997a5e56e104102fa209c6a852dd90660a20b2d9c352423edce25857fcd3704000000004847304402204e45e16932b8af514961a1d3a1a25fdf3f4f7732e9d624c6c61548ab5fb8cd410220181522ec8eca07de4860a4acdd12909d831cc56cbbac4622082221a8768d1d0901ffffffff0200ca9a3b00000000434104ae1a62fe09c5f51b13905f07f06b99a2f7159b2225f374cd378d71302fa28414e7aab37397f554a7df5f142c21c1b7303b8a0626f1baded5c72a704f7e6cd84cac00286bee0000000043410411db93e1dcdb8a016b49840f8c53bc1eb68a382e97b1482ecad7b148a6909a5cb2e0eaddfb84ccf9744464f82e160bfa9b8b64f9d4c03f999b8643f656b412a3ac00000000"
+
+// A segwit transaction with one two-item witness stack.
+const segwitTx pkg.TxHex = "0200000000010111111111111111111111111111111111111111111111111111111111111111110100000000fdffffff0250c3000000000000160014abababababababababababababababababababab39300000000000001976a914cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd88ac024730444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444444012102555555555555555555555555555555555555555555555555555555555555555510eb0900"
+
+func TestDecodeTransaction(t *testing.T) {
+	tx, err := wire.DecodeTransaction(block170Tx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tx.TxID != "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16" || tx.WTxID != tx.TxID || tx.HasWitness() {
+		t.Errorf("unexpected ids %s %s", tx.TxID, tx.WTxID)
+	}
+	if tx.Version != 1 || tx.LockTime != 0 || len(tx.Inputs) != 1 || len(tx.Outputs) != 2 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	if in := tx.Inputs[0]; in.PreviousTxID != "0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9" || in.PreviousVOut != 0 || in.Sequence != 0xffffffff || len(in.ScriptSig) != 72 || in.IsCoinbase() {
+		t.Errorf("unexpected input %+v", in)
+	}
+	if tx.Outputs[0].Value != 1000000000 || tx.Outputs[1].Value != 4000000000 || len(tx.Outputs[0].ScriptPubKey) != 67 {
+		t.Errorf("unexpected outputs %+v %+v", tx.Outputs[0], tx.Outputs[1])
+	}
+	if tx.Size != 275 || tx.Weight != 1100 || tx.VSize != 275 {
+		t.Errorf("unexpected sizes %d %d %d", tx.Size, tx.Weight, tx.VSize)
+	}
+
+	tx, err = wire.DecodeTransaction(segwitTx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tx.TxID != "5d37efc9a0f7bca924fca70059ac489cb588e535642f2b555d10c84221814c6f" || tx.WTxID != "bd561515955381a27d41fd82c69a1c17c36b4d8b689929d47ac96e8576a52bf4" || !tx.HasWitness() {
+		t.Errorf("unexpected ids %s %s", tx.TxID, tx.WTxID)
+	}
+	if tx.Version != 2 || tx.LockTime != 650000 || tx.Inputs[0].Sequence != 0xfffffffd || len(tx.Inputs[0].ScriptSig) != 0 {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+	if witness := tx.Inputs[0].Witness; len(witness) != 2 || len(witness[0]) != 71 || len(witness[1]) != 33 {
+		t.Errorf("unexpected witness %x", witness)
+	}
+	if tx.Size != 225 || tx.Weight != 573 || tx.VSize != 144 {
+		t.Errorf("unexpected sizes %d %d %d", tx.Size, tx.Weight, tx.VSize)
+	}
+}
+
+func TestDecodeTransaction_Invalid(t *testing.T) {
+	cases := map[string]pkg.TxHex{
+		"truncated":     block170Tx[:len(block170Tx)-2],
+		"trailing":      block170Tx + "00",
+		"huge count":    "01000000ffffffffffffffffff",
+		"non-canonical": "01000000fd0100" + block170Tx[10:],
+		"unknown flag":  "0100000000020100",
+		"empty witness": "01000000000101" + block170Tx[10:len(block170Tx)-8] + "00" + "00000000",
+	}
+	for name, txHex := range cases {
+		var decodeErr *pkg.DecodeError
+		if _, err := wire.DecodeTransaction(txHex); !errors.Is(err, wire.ErrInvalidTransaction) || !errors.As(err, &decodeErr) {
+			t.Errorf("%s: expected ErrInvalidTransaction, got %v", name, err)
+		}
+	}
+}
+
+func TestGetCheckedTransaction(t *testing.T) {
+	raw, err := wire.DecodeTransaction(segwitTx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tx := &pkg.Transaction{
+		ID:       raw.TxID,
+		Version:  2,
+		LockTime: 650000,
+		VIn: []*pkg.TransactionIn{{
+			ID:       "1111111111111111111111111111111111111111111111111111111111111111",
+			VOut:     1,
+			Witness:  []string{hex.EncodeToString(raw.Inputs[0].Witness[0]), hex.EncodeToString(raw.Inputs[0].Witness[1])},
+			Sequence: 0xfffffffd,
+		}},
+		VOut: []*pkg.TransactionOut{
+			{ScriptPubKey: "0014abababababababababababababababababababab", Value: 50000},
+			{ScriptPubKey: "76a914cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd88ac", Value: 12345},
+		},
+		Size:   225,
+		Weight: 573,
+	}
+
+	fake := esploratest.New()
+	fake.AddTransaction(tx)
+	fake.SetTransactionHex(tx.ID, segwitTx)
+	if _, _, err := wire.GetCheckedTransaction(context.Background(), fake, tx.ID); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tx.VOut[1].Value = 12346
+	tx.Weight = 900
+	err = wire.Compare(raw, tx)
+	if !errors.Is(err, wire.ErrInconsistentTransaction) {
+		t.Fatalf("expected ErrInconsistentTransaction, got %v", err)
+	}
+	if expected := "server transaction does not match its serialization: weight: server 900, decoded 573; vout 1 value: server 0.00012346 BTC, decoded 0.00012345 BTC"; err.Error() != expected {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+
+	fake.SetTransactionHex(tx.ID, block170Tx)
+	if _, _, err := wire.GetCheckedTransaction(context.Background(), fake, tx.ID); !errors.Is(err, wire.ErrInconsistentTransaction) {
+		t.Errorf("expected ErrInconsistentTransaction for a mismatched serialization, got %v", err)
+	}
+}